@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// VerifyAll verifies the URLs concurrently, returning their results in the
+// same order. An error verifying a URL does not stop the batch and is set on
+// the Err field of its result. At most the number of URLs set with
+// WithConcurrency are verified at once, and at most the number set with
+// WithHostConcurrency per host.
+func (v *Verifier) VerifyAll(ctx context.Context, urls []string) []*Result {
+	b := v.newBatch(ctx)
+	results := make([]*Result, len(urls))
+
+	var wg sync.WaitGroup
+	for i, u := range urls {
+		// Wait for a slot before starting the next URL, so there are no more
+		// goroutines than slots
+		release, err := b.acquire()
+		if err != nil {
+			results[i] = b.failed(u, err)
+			continue
+		}
+
+		i, u := i, u
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer release()
+			results[i] = b.verify(u)
+		}()
+	}
+	wg.Wait()
+
+	return results
+}
+
+// VerifyStream verifies the URLs received from urls concurrently, in the same
+// way as VerifyAll, sending each result as soon as it is complete, so results
+// are not necessarily in the same order as the URLs. The next URL is only
+// received once there is a slot for it, and a slot is held until its result
+// has been received, so a consumer which stops receiving results stops URLs
+// being received. Receiving stops when urls is closed or ctx is done, and the
+// returned channel is closed once the results of all URLs received have been
+// sent. Once ctx is done, results which have not been received are dropped.
+func (v *Verifier) VerifyStream(ctx context.Context, urls <-chan string) <-chan *Result {
+	b := v.newBatch(ctx)
+	results := make(chan *Result)
+
+	go func() {
+		var wg sync.WaitGroup
+		defer func() {
+			wg.Wait()
+			close(results)
+		}()
+
+		for {
+			release, err := b.acquire()
+			if err != nil {
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				release()
+				return
+			case u, ok := <-urls:
+				if !ok {
+					release()
+					return
+				}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					defer release()
+
+					ret := b.verify(u)
+					select {
+					case results <- ret:
+					case <-ctx.Done():
+					}
+				}()
+			}
+		}
+	}()
+
+	return results
+}
+
+// batch limits the number of URLs of a batch verified at once, overall and
+// per host
+type batch struct {
+	v     *Verifier
+	ctx   context.Context
+	slots chan struct{} // Slots for URLs being verified, nil if unlimited
+
+	mu    sync.Mutex            // Guards hosts
+	hosts map[string]*hostSlots // Slots for URLs being verified per host
+}
+
+// hostSlots are the slots for URLs of a host being verified
+type hostSlots struct {
+	slots chan struct{}
+	refs  int // The number of URLs holding or waiting for a slot
+}
+
+func (v *Verifier) newBatch(ctx context.Context) *batch {
+	b := &batch{v: v, ctx: ctx, hosts: map[string]*hostSlots{}}
+	if v.concurrency > 0 {
+		b.slots = make(chan struct{}, v.concurrency)
+	}
+	return b
+}
+
+// verify verifies the URL once there is a slot for its host, recording any
+// error on the result. The caller holds a slot of the batch.
+func (b *batch) verify(rawURL string) *Result {
+	host := batchHost(rawURL)
+
+	release, err := b.acquireHost(host)
+	if err != nil {
+		return b.failed(rawURL, err)
+	}
+	defer release()
+
+	ret, err := b.v.VerifyContext(b.ctx, rawURL)
+	ret.Err = err
+	return ret
+}
+
+// failed returns the result of a URL which was not verified because ctx was
+// done while waiting for a slot
+func (b *batch) failed(rawURL string, err error) *Result {
+	host := batchHost(rawURL)
+	return &Result{URL: rawURL, Err: &Error{URL: rawURL, Host: host, Kind: errorKind(err), Err: err}}
+}
+
+// acquire waits for a slot of the batch
+func (b *batch) acquire() (func(), error) {
+	if b.slots == nil {
+		return func() {}, nil
+	}
+
+	select {
+	case b.slots <- struct{}{}:
+	case <-b.ctx.Done():
+		return nil, b.ctx.Err()
+	}
+	return func() { <-b.slots }, nil
+}
+
+// acquireHost waits for a slot for a URL of the host. URLs without a host,
+// which are not checked via HTTP, and verifiers without a per-host limit do not
+// need one.
+func (b *batch) acquireHost(host string) (func(), error) {
+	if host == "" || b.v.hostConcurrency <= 0 {
+		return func() {}, nil
+	}
+
+	b.mu.Lock()
+	h, ok := b.hosts[host]
+	if !ok {
+		h = &hostSlots{slots: make(chan struct{}, b.v.hostConcurrency)}
+		b.hosts[host] = h
+	}
+	h.refs++
+	b.mu.Unlock()
+
+	done := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		h.refs--
+		if h.refs == 0 {
+			delete(b.hosts, host)
+		}
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+	case <-b.ctx.Done():
+		done()
+		return nil, b.ctx.Err()
+	}
+
+	return func() {
+		<-h.slots
+		done()
+	}, nil
+}
+
+// batchHost returns the host of the URL the per-host limit applies to, or an
+// empty string if it does not have one
+func batchHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+}
@@ -0,0 +1,250 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// concurrencyServer is a test server which records the maximum number of
+// requests it handled at once, overall and per host
+type concurrencyServer struct {
+	*httptest.Server
+
+	mu      sync.Mutex
+	current map[string]int
+	total   int
+	max     int
+	maxHost map[string]int
+}
+
+func newConcurrencyServer() *concurrencyServer {
+	s := &concurrencyServer{current: map[string]int{}, maxHost: map[string]int{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.URL.Query().Get("host")
+
+		s.mu.Lock()
+		s.current[host]++
+		s.total++
+		if s.current[host] > s.maxHost[host] {
+			s.maxHost[host] = s.current[host]
+		}
+		if s.total > s.max {
+			s.max = s.total
+		}
+		s.mu.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		s.mu.Lock()
+		s.current[host]--
+		s.total--
+		s.mu.Unlock()
+	}))
+	return s
+}
+
+// urls returns n URLs for each host, which resolve to the server
+func (s *concurrencyServer) urls(t *testing.T, n int, hosts ...string) []string {
+	tsURL, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urls := []string{}
+	for i := 0; i < n; i++ {
+		for _, host := range hosts {
+			urls = append(urls, fmt.Sprintf("http://%s:%s/%d?host=%s", host, tsURL.Port(), i, host))
+		}
+	}
+	return urls
+}
+
+func TestVerifyAll(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer ts.Close()
+
+	urls := []string{
+		ts.URL + "/",
+		"mailto:user@example.com",
+		ts.URL + "/missing",
+		"not a url",
+	}
+
+	verifier := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal())
+	results := verifier.VerifyAll(context.Background(), urls)
+
+	if assert.Len(t, results, len(urls)) {
+		for i, ret := range results {
+			assert.Equal(t, urls[i], ret.URL)
+		}
+
+		assert.Nil(t, results[0].Err)
+		assert.True(t, results[0].HTTP.IsSuccess)
+
+		assert.ErrorIs(t, results[1].Err, ErrUnsupportedScheme)
+
+		assert.Nil(t, results[2].Err)
+		assert.Equal(t, http.StatusNotFound, results[2].HTTP.StatusCode)
+
+		assert.ErrorIs(t, results[3].Err, ErrUnsupportedScheme)
+		assert.False(t, results[3].IsURL)
+	}
+}
+
+func TestVerifyAll_Concurrency(t *testing.T) {
+	ts := newConcurrencyServer()
+	defer ts.Close()
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+		WithConcurrency(3),
+		WithHostConcurrency(0),
+	)
+	results := verifier.VerifyAll(context.Background(), ts.urls(t, 10, "a.example"))
+
+	assert.Len(t, results, 10)
+	for _, ret := range results {
+		assert.Nil(t, ret.Err)
+	}
+	assert.Equal(t, 3, ts.max)
+}
+
+func TestVerifyAll_HostConcurrency(t *testing.T) {
+	ts := newConcurrencyServer()
+	defer ts.Close()
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+		WithConcurrency(4),
+		WithHostConcurrency(1),
+	)
+	results := verifier.VerifyAll(context.Background(), ts.urls(t, 5, "a.example", "b.example", "A.example"))
+
+	assert.Len(t, results, 15)
+	for _, ret := range results {
+		assert.Nil(t, ret.Err)
+	}
+
+	// Hosts are case-insensitive, so a.example and A.example share a limit
+	assert.Equal(t, 2, ts.max)
+	assert.LessOrEqual(t, ts.maxHost["a.example"]+ts.maxHost["A.example"], 2)
+	assert.Equal(t, 1, ts.maxHost["b.example"])
+}
+
+func TestVerifyAll_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(blockingResolver{}), WithConcurrency(1))
+	results := verifier.VerifyAll(ctx, []string{"https://a.example/", "https://b.example/", "https://c.example/"})
+
+	assert.Len(t, results, 3)
+	for _, ret := range results {
+		assert.ErrorIs(t, ret.Err, ErrCanceled)
+	}
+}
+
+func TestVerifyStream(t *testing.T) {
+	ts := newConcurrencyServer()
+	defer ts.Close()
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+		WithConcurrency(2),
+	)
+
+	urls := ts.urls(t, 3, "a.example", "b.example")
+	in := make(chan string)
+	go func() {
+		defer close(in)
+		for _, u := range urls {
+			in <- u
+		}
+	}()
+
+	received := []string{}
+	for ret := range verifier.VerifyStream(context.Background(), in) {
+		assert.Nil(t, ret.Err)
+		received = append(received, ret.URL)
+	}
+
+	sort.Strings(urls)
+	sort.Strings(received)
+	assert.Equal(t, urls, received)
+	assert.Equal(t, 2, ts.max)
+}
+
+func TestVerifyStream_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(blockingResolver{}))
+
+	// The URL being verified is canceled, and the results channel is closed
+	// without the URLs channel being closed. The canceled result may be
+	// dropped as ctx is done.
+	in := make(chan string)
+	results := verifier.VerifyStream(ctx, in)
+	in <- "https://a.example/"
+	cancel()
+
+	received := []*Result{}
+	for ret := range results {
+		received = append(received, ret)
+	}
+
+	assert.LessOrEqual(t, len(received), 1)
+	for _, ret := range received {
+		assert.ErrorIs(t, ret.Err, ErrCanceled)
+	}
+}
+
+func TestVerifyStream_Backpressure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	verifier := NewVerifier(WithConcurrency(2))
+
+	// Results are not received, so only a URL per slot is received
+	var sent int32
+	in := make(chan string)
+	go func() {
+		for i := 0; i < 10; i++ {
+			select {
+			case in <- fmt.Sprintf("https://example.com/%d", i):
+				atomic.AddInt32(&sent, 1)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	results := verifier.VerifyStream(ctx, in)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&sent))
+
+	// The results channel is closed once ctx is done, even though the results
+	// were not received
+	cancel()
+	for range results {
+	}
+}
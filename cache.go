@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Cache stores the results of Verify, keyed by the normalized URL and the
+// configuration of the verifier. Implementations must be safe for concurrent
+// use.
+type Cache interface {
+	// Get returns the entry of the key, if it is cached and has not expired
+	Get(key string) (CacheEntry, bool)
+	// Set caches the entry of the key for the TTL
+	Set(key string, entry CacheEntry, ttl time.Duration)
+}
+
+// CacheEntry is the result of verifying a URL, as stored in a Cache
+type CacheEntry struct {
+	Result *Result // The result of the verification
+	Err    error   // The error verifying the URL, if any
+}
+
+// LRUCache is an in-memory Cache which evicts the least recently used entries
+// once it is full. Create one using NewLRUCache().
+type LRUCache struct {
+	size int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+// lruEntry is an entry of the LRUCache
+type lruEntry struct {
+	key     string
+	entry   CacheEntry
+	expires time.Time
+}
+
+// NewLRUCache creates an LRUCache holding at most size entries
+func NewLRUCache(size int) *LRUCache {
+	if size < 1 {
+		size = 1
+	}
+	return &LRUCache{size: size, entries: map[string]*list.Element{}, order: list.New()}
+}
+
+// Get returns the entry of the key, if it is cached and has not expired
+func (c *LRUCache) Get(key string) (CacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return CacheEntry{}, false
+	}
+
+	e := el.Value.(*lruEntry)
+	if !time.Now().Before(e.expires) {
+		c.order.Remove(el)
+		delete(c.entries, key)
+		return CacheEntry{}, false
+	}
+
+	c.order.MoveToFront(el)
+	return e.entry, true
+}
+
+// Set caches the entry of the key for the TTL, evicting the least recently used
+// entry if the cache is full
+func (c *LRUCache) Set(key string, entry CacheEntry, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := time.Now().Add(ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		e.entry = entry
+		e.expires = expires
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&lruEntry{key: key, entry: entry, expires: expires})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// Len returns the number of entries in the cache, including expired entries
+// which have not been evicted yet
+func (c *LRUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// flightGroup collapses concurrent verifications of the same key into one
+type flightGroup struct {
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+// flightCall is a verification in flight
+type flightCall struct {
+	done  chan struct{} // Closed when the verification is complete
+	entry CacheEntry
+	ok    bool // Whether the result can be shared, i.e. the context of the verification was not done
+}
+
+// do verifies the key with fn, unless it is already being verified, in which
+// case it waits for that verification instead. If the verification waited for
+// is cut short by its own context, fn is called.
+func (g *flightGroup) do(ctx context.Context, key string, fn func() (CacheEntry, bool)) (CacheEntry, bool, error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = map[string]*flightCall{}
+	}
+	if c, ok := g.calls[key]; ok {
+		g.mu.Unlock()
+
+		select {
+		case <-c.done:
+			if c.ok {
+				return c.entry, true, nil
+			}
+			entry, _ := fn()
+			return entry, false, nil
+		case <-ctx.Done():
+			return CacheEntry{}, false, ctx.Err()
+		}
+	}
+
+	c := &flightCall{done: make(chan struct{})}
+	g.calls[key] = c
+	g.mu.Unlock()
+
+	defer func() {
+		g.mu.Lock()
+		delete(g.calls, key)
+		g.mu.Unlock()
+		close(c.done)
+	}()
+
+	c.entry, c.ok = fn()
+	return c.entry, false, nil
+}
+
+// verifyCached verifies the URL, returning the cached result if there is one
+// and collapsing concurrent verifications of the same URL. Results are cached
+// unless the verification was cut short by the context. The cache holds a copy
+// of the result, so callers may modify the result they are returned, e.g. by
+// setting Err.
+func (v *Verifier) verifyCached(ctx context.Context, rawURL string) (*Result, error) {
+	key := v.cacheKey(rawURL)
+
+	if entry, ok := v.cache.Get(key); ok {
+		return v.cachedResult(entry, rawURL)
+	}
+
+	var ret *Result
+	var err error
+	entry, shared, flightErr := v.flights.do(ctx, key, func() (CacheEntry, bool) {
+		ret, err = v.verify(ctx, rawURL)
+		entry := CacheEntry{Result: cloneResult(ret), Err: err}
+
+		if ctx.Err() != nil || errors.Is(err, ErrCanceled) {
+			return entry, false
+		}
+
+		ttl := v.cacheTTL
+		if err != nil || !ret.IsURL || (ret.HTTP != nil && !ret.HTTP.IsSuccess) {
+			ttl = v.negativeCacheTTL
+		}
+		if ttl > 0 {
+			v.cache.Set(key, entry, ttl)
+		}
+		return entry, true
+	})
+	if flightErr != nil {
+		return &Result{URL: rawURL}, &Error{URL: rawURL, Kind: errorKind(flightErr), Err: flightErr}
+	}
+	if shared {
+		return v.cachedResult(entry, rawURL)
+	}
+	return ret, err
+}
+
+// cachedResult returns a copy of the cached result for the URL. The fields
+// derived from the URL alone are set again, as the URL may differ from the one
+// the result was cached for while having the same key, and the verifier may
+// differ from the one which cached it, e.g. in its Public Suffix List.
+func (v *Verifier) cachedResult(entry CacheEntry, rawURL string) (*Result, error) {
+	parsed := Result{URL: rawURL}
+	v.parse(&parsed) //nolint:errcheck // The URL has the same key, so it parses
+
+	ret := cloneResult(entry.Result)
+	ret.Cached = true
+	ret.Err = nil
+	ret.URL = parsed.URL
+	ret.URLComponents = parsed.URLComponents
+	ret.NormalizedURL = parsed.NormalizedURL
+	ret.CleanURL = parsed.CleanURL
+	ret.RemovedParams = parsed.RemovedParams
+	ret.Domain = parsed.Domain
+	ret.HasKnownTLD = parsed.HasKnownTLD
+	ret.IsURL = parsed.IsURL
+	ret.IsRFC3986URL = parsed.IsRFC3986URL
+	ret.IsRFC3986URI = parsed.IsRFC3986URI
+	return ret, entry.Err
+}
+
+// cloneResult returns a deep copy of the result, so the copy held by the cache
+// and the copies returned to callers do not share anything a caller could
+// modify
+func cloneResult(ret *Result) *Result {
+	cp := *ret
+	if ret.URLComponents != nil {
+		u := *ret.URLComponents
+		cp.URLComponents = &u
+	}
+	if ret.RemovedParams != nil {
+		cp.RemovedParams = append(make([]string, 0, len(ret.RemovedParams)), ret.RemovedParams...)
+	}
+	if ret.Domain != nil {
+		d := *ret.Domain
+		if d.Subdomains != nil {
+			d.Subdomains = append(make([]string, 0, len(d.Subdomains)), d.Subdomains...)
+		}
+		cp.Domain = &d
+	}
+	if ret.HTTP != nil {
+		h := *ret.HTTP
+		if h.Redirects != nil {
+			h.Redirects = append(make([]Hop, 0, len(h.Redirects)), h.Redirects...)
+		}
+		if h.Attempts != nil {
+			h.Attempts = append(make([]Attempt, 0, len(h.Attempts)), h.Attempts...)
+		}
+		if h.TLS != nil {
+			t := *h.TLS
+			if t.SANs != nil {
+				t.SANs = append(make([]string, 0, len(t.SANs)), t.SANs...)
+			}
+			h.TLS = &t
+		}
+		if h.Timing != nil {
+			t := *h.Timing
+			h.Timing = &t
+		}
+		cp.HTTP = &h
+	}
+	if ret.Refused != nil {
+		r := *ret.Refused
+		if r.IP != nil {
+			r.IP = append(make(net.IP, 0, len(r.IP)), r.IP...)
+		}
+		cp.Refused = &r
+	}
+	return &cp
+}
+
+// cacheKey returns the cache key of the URL: the configuration of the verifier
+// which affects the result, and the URL normalized with the safe
+// normalizations of Normalize. Resolvers, clients, transports and TLD lists are
+// keyed by identity, as they cannot be compared by value.
+func (v *Verifier) cacheKey(rawURL string) string {
+	normalized, err := Normalize(rawURL, NormalizeOptions{})
+	if err != nil {
+		normalized = rawURL
+	}
+
+	return fmt.Sprintf("%t|%t|%t|%v|%v|%d|%s|%v|%d|%q|%s|%s|%s|%s|%+v|%+v|%t|%v|%s|%t %s",
+		v.httpCheckEnabled, v.allowHttpCheckInternal, v.skipCertVerification,
+		v.allowedCIDRs, v.deniedCIDRs, v.maxRedirects,
+		v.methodStrategy, v.getOnlyHosts, v.maxBodyBytes, v.userAgent,
+		v.timeout, cacheIdentity(v.resolver), cacheIdentity(v.httpClient), cacheIdentity(v.transport),
+		v.retryPolicy, v.normalizeOptions, v.stripTracking, v.trackingRules,
+		cacheIdentity(v.tldList), v.requireKnownTLD, normalized)
+}
+
+// cacheIdentity returns the identity of a value in a cache key: its type and
+// address if it is a pointer or similar, or its type and value otherwise
+func cacheIdentity(x interface{}) string {
+	rv := reflect.ValueOf(x)
+	switch rv.Kind() {
+	case reflect.Invalid:
+		return "nil"
+	case reflect.Chan, reflect.Func, reflect.Map, reflect.Pointer, reflect.Slice, reflect.UnsafePointer:
+		if rv.IsNil() {
+			return "nil"
+		}
+		return fmt.Sprintf("%T@%x", x, rv.Pointer())
+	}
+	return fmt.Sprintf("%T%+v", x, x)
+}
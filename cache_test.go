@@ -0,0 +1,341 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingServer is a test server which counts the requests it handles
+func countingServer(handler http.HandlerFunc) (*httptest.Server, *int32) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if handler != nil {
+			handler(w, r)
+		}
+	}))
+	return ts, &requests
+}
+
+func TestVerify_Cache(t *testing.T) {
+	ts, requests := countingServer(nil)
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(NewLRUCache(10)))
+
+	ret, err := verifier.Verify(ts.URL + "/")
+	assert.Nil(t, err)
+	assert.False(t, ret.Cached)
+	assert.True(t, ret.HTTP.IsSuccess)
+
+	// The scheme and host are case-insensitive
+	cachedURL := strings.Replace(ts.URL, "http://", "HTTP://", 1) + "/"
+	ret, err = verifier.Verify(cachedURL)
+	assert.Nil(t, err)
+	assert.True(t, ret.Cached)
+	assert.Equal(t, cachedURL, ret.URL)
+	assert.True(t, ret.HTTP.IsSuccess)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestVerify_CacheTTL(t *testing.T) {
+	ts, requests := countingServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	})
+	defer ts.Close()
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithCache(NewLRUCache(10)),
+		WithCacheTTL(time.Hour, 20*time.Millisecond),
+	)
+
+	for i := 0; i < 2; i++ {
+		_, err := verifier.Verify(ts.URL + "/")
+		assert.Nil(t, err)
+		_, err = verifier.Verify(ts.URL + "/missing")
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+
+	// Only the unsuccessful result has expired
+	time.Sleep(30 * time.Millisecond)
+	_, err := verifier.Verify(ts.URL + "/")
+	assert.Nil(t, err)
+	ret, err := verifier.Verify(ts.URL + "/missing")
+	assert.Nil(t, err)
+	assert.False(t, ret.Cached)
+	assert.Equal(t, int32(3), atomic.LoadInt32(requests))
+}
+
+func TestVerify_CacheNegativeTTLDisabled(t *testing.T) {
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithResolver(notFoundResolver{}),
+		WithCache(NewLRUCache(10)),
+		WithCacheTTL(time.Hour, 0),
+	)
+
+	for i := 0; i < 2; i++ {
+		ret, err := verifier.Verify("https://example.unreachable/")
+		assert.ErrorIs(t, err, ErrDNSFailure)
+		assert.False(t, ret.Cached)
+	}
+}
+
+func TestVerify_CacheError(t *testing.T) {
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(notFoundResolver{}), WithCache(NewLRUCache(10)))
+
+	_, err := verifier.Verify("https://example.unreachable/")
+	assert.ErrorIs(t, err, ErrDNSFailure)
+
+	ret, err := verifier.Verify("https://example.unreachable/")
+	assert.ErrorIs(t, err, ErrDNSFailure)
+	assert.True(t, ret.Cached)
+}
+
+func TestVerify_CacheConfig(t *testing.T) {
+	ts, requests := countingServer(nil)
+	defer ts.Close()
+
+	cache := NewLRUCache(10)
+
+	// Verifiers with different configurations sharing a cache do not share
+	// results
+	ret, err := NewVerifier(WithCache(cache)).Verify(ts.URL)
+	assert.Nil(t, err)
+	assert.Nil(t, ret.HTTP)
+
+	ret, err = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(cache)).Verify(ts.URL)
+	assert.Nil(t, err)
+	assert.False(t, ret.Cached)
+	assert.NotNil(t, ret.HTTP)
+
+	ret, err = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(cache)).Verify(ts.URL)
+	assert.Nil(t, err)
+	assert.True(t, ret.Cached)
+
+	ret, err = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(cache), WithNormalizeOptions(NormalizeOptions{SortQuery: true})).Verify(ts.URL)
+	assert.Nil(t, err)
+	assert.False(t, ret.Cached)
+
+	assert.Equal(t, int32(2), atomic.LoadInt32(requests))
+	assert.Equal(t, 3, cache.Len())
+}
+
+func TestVerify_CacheCopies(t *testing.T) {
+	ts, _ := countingServer(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+		}
+	})
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(NewLRUCache(10)))
+
+	// Modifying a result, including the first one, does not modify the results
+	// of later cache hits
+	ret, err := verifier.Verify(ts.URL + "/")
+	assert.Nil(t, err)
+	ret.HTTP.StatusCode = 0
+	ret.HTTP.Redirects[0].Location = "modified"
+
+	hit, err := verifier.Verify(ts.URL + "/")
+	assert.Nil(t, err)
+	assert.True(t, hit.Cached)
+	hit.HTTP.FinalURL = "modified"
+	hit.HTTP.Redirects[0].StatusCode = 0
+	hit.HTTP.Timing.Total = 0
+
+	hit, err = verifier.Verify(ts.URL + "/")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, hit.HTTP.StatusCode)
+	assert.Equal(t, ts.URL+"/final", hit.HTTP.FinalURL)
+	if assert.Len(t, hit.HTTP.Redirects, 1) {
+		assert.Equal(t, "/final", hit.HTTP.Redirects[0].Location)
+		assert.Equal(t, http.StatusFound, hit.HTTP.Redirects[0].StatusCode)
+	}
+	assert.NotZero(t, hit.HTTP.Timing.Total)
+}
+
+func TestVerify_CacheResolver(t *testing.T) {
+	ts, requests := countingServer(nil)
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rawURL := "http://a.example:" + tsURL.Port() + "/"
+	cache := NewLRUCache(10)
+
+	// Verifiers with different resolvers sharing a cache do not share results
+	_, err = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithResolver(notFoundResolver{}), WithCache(cache)).Verify(rawURL)
+	assert.ErrorIs(t, err, ErrDNSFailure)
+
+	resolver := &stubResolver{responses: [][]string{{"127.0.0.1"}}}
+	ret, err := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithResolver(resolver), WithCache(cache)).Verify(rawURL)
+	assert.Nil(t, err)
+	assert.False(t, ret.Cached)
+
+	ret, err = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithResolver(resolver), WithCache(cache)).Verify(rawURL)
+	assert.Nil(t, err)
+	assert.True(t, ret.Cached)
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+}
+
+func TestVerifyAll_Cache(t *testing.T) {
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(notFoundResolver{}), WithCache(NewLRUCache(10)))
+
+	urls := make([]string, 50)
+	for i := range urls {
+		urls[i] = "https://example.unreachable/"
+	}
+
+	// Each result is a copy, so setting Err on one does not race with others
+	for _, ret := range verifier.VerifyAll(context.Background(), urls) {
+		assert.ErrorIs(t, ret.Err, ErrDNSFailure)
+	}
+
+	ret, err := verifier.Verify("https://example.unreachable/")
+	assert.ErrorIs(t, err, ErrDNSFailure)
+	assert.True(t, ret.Cached)
+	assert.Nil(t, ret.Err)
+}
+
+func TestVerify_CacheInFlight(t *testing.T) {
+	release := make(chan struct{})
+	ts, requests := countingServer(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithCache(NewLRUCache(10)))
+
+	var wg sync.WaitGroup
+	results := make([]*Result, 10)
+	for i := range results {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ret, err := verifier.Verify(ts.URL)
+			assert.Nil(t, err)
+			results[i] = ret
+		}()
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), atomic.LoadInt32(requests))
+
+	cached := 0
+	for _, ret := range results {
+		assert.True(t, ret.HTTP.IsSuccess)
+		if ret.Cached {
+			cached++
+		}
+	}
+	assert.Equal(t, 9, cached)
+}
+
+func TestVerify_CacheCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(blockingResolver{}), WithCache(NewLRUCache(10)))
+
+	_, err := verifier.VerifyContext(ctx, "https://example.com/")
+	assert.ErrorIs(t, err, ErrCanceled)
+
+	// The canceled result is not cached
+	ctx, cancel = context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	ret, err := verifier.VerifyContext(ctx, "https://example.com/")
+	assert.ErrorIs(t, err, ErrTimeout)
+	assert.False(t, ret.Cached)
+}
+
+func TestLRUCache(t *testing.T) {
+	cache := NewLRUCache(2)
+	a := CacheEntry{Result: &Result{URL: "a"}}
+	b := CacheEntry{Result: &Result{URL: "b"}}
+	c := CacheEntry{Result: &Result{URL: "c"}}
+
+	cache.Set("a", a, time.Hour)
+	cache.Set("b", b, time.Hour)
+
+	// a is used, so b is evicted
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	cache.Set("c", c, time.Hour)
+
+	entry, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, a, entry)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	entry, ok = cache.Get("c")
+	assert.True(t, ok)
+	assert.Equal(t, c, entry)
+	assert.Equal(t, 2, cache.Len())
+
+	// Expired entries are removed
+	cache.Set("a", a, -time.Second)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, cache.Len())
+}
+
+func TestVerify_CacheURLFields(t *testing.T) {
+	verifier := NewVerifier(WithStripTracking(), WithCache(NewLRUCache(10)))
+
+	_, err := verifier.Verify("https://example.com/a/../?utm_source=x")
+	assert.Nil(t, err)
+
+	// The fields derived from the URL are those of the URL verified, not the
+	// one the result was cached for
+	ret, err := verifier.Verify("https://Example.com/?utm_source=x")
+	assert.Nil(t, err)
+	assert.True(t, ret.Cached)
+	assert.Equal(t, "Example.com", ret.URLComponents.Host)
+	assert.Equal(t, "https://Example.com/", ret.CleanURL)
+	assert.Equal(t, "https://example.com/?utm_source=x", ret.NormalizedURL)
+}
+
+func TestVerify_CacheDomain(t *testing.T) {
+	cache := NewLRUCache(10)
+
+	ret, err := NewVerifier(WithCache(cache)).Verify("https://a.example.co.uk/")
+	assert.Nil(t, err)
+	assert.Equal(t, "example.co.uk", ret.Domain.RegistrableDomain)
+
+	// The Domain of a cached result is that of the verifier's list
+	list, err := ParsePublicSuffixList(strings.NewReader("example.co.uk\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err = NewVerifier(WithCache(cache), WithPublicSuffixList(list)).Verify("https://a.example.co.uk/")
+	assert.Nil(t, err)
+	assert.True(t, ret.Cached)
+	assert.Equal(t, "a.example.co.uk", ret.Domain.RegistrableDomain)
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: MIT
+
+// Command urlverifier-server serves the URL verification JSON API of the
+// server package. HTTP checks to hosts that resolve to internal IPs are always
+// refused.
+//
+// Usage:
+//
+//	urlverifier-server [flags]
+//
+// The flags are:
+//
+//	--addr address
+//		The address to listen on (default :8080)
+//	--http
+//		Check if the URLs are reachable via HTTP (default true)
+//	--timeout duration
+//		The maximum duration of each HTTP check (default 30s)
+//	--max-request-bytes n
+//		The maximum size of a request body (default 1048576)
+//	--max-batch n
+//		The maximum number of URLs of a batch request (default 100)
+//	--rate-limit rps
+//		The number of URLs each client may verify per second, 0 for no limit (default 10)
+//	--rate-burst n
+//		The number of URLs each client may verify at once above the rate limit (default 100)
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	urlverifier "github.com/davidmytton/url-verifier"
+	"github.com/davidmytton/url-verifier/server"
+)
+
+// shutdownTimeout is how long requests in flight are given to complete when the
+// server is stopped
+const shutdownTimeout = 30 * time.Second
+
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	httpCheck := flag.Bool("http", true, "check if the URLs are reachable via HTTP")
+	timeout := flag.Duration("timeout", urlverifier.DefaultTimeout, "the maximum duration of each HTTP check")
+	maxRequestBytes := flag.Int64("max-request-bytes", server.DefaultMaxRequestBytes, "the maximum size of a request body")
+	maxBatch := flag.Int("max-batch", server.DefaultMaxBatchSize, "the maximum number of URLs of a batch request")
+	rateLimit := flag.Float64("rate-limit", server.DefaultRateLimit, "the number of URLs each client may verify per second, 0 for no limit")
+	rateBurst := flag.Int("rate-burst", server.DefaultRateLimitBurst, "the number of URLs each client may verify at once above the rate limit")
+	flag.Parse()
+
+	verifierOpts := []urlverifier.Option{urlverifier.WithTimeout(*timeout)}
+	if *httpCheck {
+		verifierOpts = append(verifierOpts, urlverifier.WithHTTPCheck())
+	}
+
+	srv := server.New(
+		server.WithVerifierOptions(verifierOpts...),
+		server.WithMaxRequestBytes(*maxRequestBytes),
+		server.WithMaxBatchSize(*maxBatch),
+		server.WithRateLimit(*rateLimit, *rateBurst),
+	)
+	defer srv.Close()
+
+	httpServer := &http.Server{
+		Addr:              *addr,
+		Handler:           srv,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("shutting down: %s", err)
+		}
+	}()
+
+	log.Printf("listening on %s", *addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,244 @@
+// SPDX-License-Identifier: MIT
+
+// Command urlverifier verifies URLs given as arguments, or newline-separated on
+// stdin, and prints the results. It exits with status 1 if any URL fails
+// verification and 2 if it is used incorrectly.
+//
+// Usage:
+//
+//	urlverifier [flags] [url ...]
+//
+// The flags are:
+//
+//	--http
+//		Check if the URLs are reachable via HTTP
+//	--allow-internal
+//		Allow HTTP checks to hosts that resolve to internal IPs
+//	--insecure
+//		Skip certificate verification of HTTP checks
+//	--timeout duration
+//		The maximum duration of each HTTP check, 0 for no timeout (default 30s)
+//	--concurrency n
+//		The maximum number of URLs verified at once, 0 for no limit (default 10)
+//	--format table|json|ndjson
+//		The output format (default table)
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	urlverifier "github.com/davidmytton/url-verifier"
+)
+
+// Exit codes
+const (
+	exitPass  = 0 // All URLs passed verification
+	exitFail  = 1 // At least one URL failed verification
+	exitUsage = 2 // The command was used incorrectly
+)
+
+// Output formats
+const (
+	formatTable  = "table"
+	formatJSON   = "json"
+	formatNDJSON = "ndjson"
+)
+
+// output is a result as printed in the JSON and NDJSON formats
+type output struct {
+	*urlverifier.Result
+	Passed bool   `json:"passed"`          // Whether the URL passed verification
+	Error  string `json:"error,omitempty"` // The error verifying the URL, if any
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run runs the command with the given arguments and streams, returning the exit
+// code
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("urlverifier", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: urlverifier [flags] [url ...]")
+		fmt.Fprintln(stderr, "\nVerifies the URLs given as arguments, or newline-separated on stdin.\n\nFlags:")
+		flags.PrintDefaults()
+	}
+
+	httpCheck := flags.Bool("http", false, "check if the URLs are reachable via HTTP")
+	allowInternal := flags.Bool("allow-internal", false, "allow HTTP checks to hosts that resolve to internal IPs")
+	insecure := flags.Bool("insecure", false, "skip certificate verification of HTTP checks")
+	timeout := flags.Duration("timeout", urlverifier.DefaultTimeout, "the maximum duration of each HTTP check, 0 for no timeout")
+	concurrency := flags.Int("concurrency", urlverifier.DefaultConcurrency, "the maximum number of URLs verified at once, 0 for no limit")
+	format := flags.String("format", formatTable, "the output format: table, json or ndjson")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitPass
+		}
+		return exitUsage
+	}
+
+	switch *format {
+	case formatTable, formatJSON, formatNDJSON:
+	default:
+		fmt.Fprintf(stderr, "urlverifier: unknown format %q\n", *format)
+		return exitUsage
+	}
+
+	opts := []urlverifier.Option{
+		urlverifier.WithTimeout(*timeout),
+		urlverifier.WithConcurrency(*concurrency),
+	}
+	if *httpCheck {
+		opts = append(opts, urlverifier.WithHTTPCheck())
+	}
+	if *allowInternal {
+		opts = append(opts, urlverifier.WithHTTPCheckInternal())
+	}
+	if *insecure {
+		opts = append(opts, urlverifier.WithSkipCertVerification())
+	}
+	verifier := urlverifier.NewVerifier(opts...)
+	defer verifier.Close()
+
+	urls := make(chan string)
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(urls)
+		readErr <- readURLs(ctx, flags.Args(), stdin, urls)
+	}()
+
+	passed := true
+	var err error
+	if *format == formatNDJSON {
+		// Results are printed as soon as they are complete
+		enc := json.NewEncoder(stdout)
+		for ret := range verifier.VerifyStream(ctx, urls) {
+			o := newOutput(ret)
+			passed = passed && o.Passed
+			if encErr := enc.Encode(o); encErr != nil && err == nil {
+				err = encErr
+			}
+		}
+	} else {
+		list := []string{}
+		for u := range urls {
+			list = append(list, u)
+		}
+
+		outputs := []*output{}
+		for _, ret := range verifier.VerifyAll(ctx, list) {
+			o := newOutput(ret)
+			passed = passed && o.Passed
+			outputs = append(outputs, o)
+		}
+
+		if *format == formatJSON {
+			enc := json.NewEncoder(stdout)
+			enc.SetIndent("", "  ")
+			err = enc.Encode(outputs)
+		} else {
+			err = printTable(stdout, outputs)
+		}
+	}
+
+	if rErr := <-readErr; rErr != nil {
+		fmt.Fprintf(stderr, "urlverifier: reading URLs: %s\n", rErr)
+		return exitFail
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "urlverifier: %s\n", err)
+		return exitFail
+	}
+	if !passed {
+		return exitFail
+	}
+	return exitPass
+}
+
+// readURLs sends the URLs given as arguments, or if there are none, the
+// non-empty lines of stdin
+func readURLs(ctx context.Context, args []string, stdin io.Reader, urls chan<- string) error {
+	send := func(u string) bool {
+		select {
+		case urls <- u:
+			return true
+		case <-ctx.Done():
+			return false
+		}
+	}
+
+	if len(args) > 0 {
+		for _, u := range args {
+			if !send(u) {
+				return nil
+			}
+		}
+		return nil
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		u := strings.TrimSpace(scanner.Text())
+		if u == "" {
+			continue
+		}
+		if !send(u) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// newOutput returns the output of a result. A URL passes verification if it is
+// valid, there was no error and, if checked via HTTP, the check was successful.
+func newOutput(ret *urlverifier.Result) *output {
+	o := &output{Result: ret, Passed: ret.Err == nil && ret.IsURL}
+	if ret.Err != nil {
+		o.Error = ret.Err.Error()
+	}
+	if ret.HTTP != nil && !ret.HTTP.IsSuccess {
+		o.Passed = false
+	}
+	return o
+}
+
+// printTable prints the outputs as a table
+func printTable(w io.Writer, outputs []*output) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "URL\tVALID\tSTATUS\tRESULT")
+
+	for _, o := range outputs {
+		status := "-"
+		if o.HTTP != nil && o.HTTP.StatusCode != 0 {
+			status = strconv.Itoa(o.HTTP.StatusCode)
+		}
+
+		result := "pass"
+		switch {
+		case o.Error != "":
+			result = o.Error
+		case !o.Passed:
+			result = "fail"
+		}
+
+		fmt.Fprintf(tw, "%s\t%t\t%s\t%s\n", o.URL, o.IsURL, status, result)
+	}
+	return tw.Flush()
+}
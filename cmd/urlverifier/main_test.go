@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: MIT
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestRun_Table(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"https://example.com/", "not a url"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitFail, code)
+	assert.Empty(t, stderr.String())
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+	if assert.Len(t, lines, 3) {
+		assert.Equal(t, []string{"URL", "VALID", "STATUS", "RESULT"}, strings.Fields(lines[0]))
+		assert.Equal(t, []string{"https://example.com/", "true", "-", "pass"}, strings.Fields(lines[1]))
+		assert.Equal(t, []string{"not", "a", "url", "false", "-", "fail"}, strings.Fields(lines[2]))
+	}
+}
+
+func TestRun_HTTP(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"--http", "--allow-internal", "--format", "json", ts.URL + "/", ts.URL + "/missing"}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitFail, code)
+
+	var outputs []map[string]interface{}
+	if assert.Nil(t, json.Unmarshal(stdout.Bytes(), &outputs)) && assert.Len(t, outputs, 2) {
+		assert.Equal(t, ts.URL+"/", outputs[0]["url"])
+		assert.Equal(t, true, outputs[0]["passed"])
+		assert.Equal(t, float64(http.StatusOK), outputs[0]["http"].(map[string]interface{})["status_code"])
+
+		assert.Equal(t, ts.URL+"/missing", outputs[1]["url"])
+		assert.Equal(t, false, outputs[1]["passed"])
+		assert.Equal(t, float64(http.StatusNotFound), outputs[1]["http"].(map[string]interface{})["status_code"])
+	}
+}
+
+func TestRun_HTTPInternalRefused(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"--http", "--format", "json", ts.URL}, nil, &stdout, &stderr)
+
+	assert.Equal(t, exitFail, code)
+
+	var outputs []map[string]interface{}
+	if assert.Nil(t, json.Unmarshal(stdout.Bytes(), &outputs)) && assert.Len(t, outputs, 1) {
+		assert.Equal(t, false, outputs[0]["passed"])
+		assert.Contains(t, outputs[0]["error"], "internal IP")
+	}
+}
+
+func TestRun_StdinNDJSON(t *testing.T) {
+	ts := newTestServer()
+	defer ts.Close()
+
+	stdin := strings.NewReader(ts.URL + "/a\n\n  " + ts.URL + "/b  \n")
+
+	var stdout, stderr bytes.Buffer
+	code := run(context.Background(), []string{"--http", "--allow-internal", "--format", "ndjson"}, stdin, &stdout, &stderr)
+
+	assert.Equal(t, exitPass, code)
+
+	urls := []string{}
+	for _, line := range strings.Split(strings.TrimSpace(stdout.String()), "\n") {
+		var o map[string]interface{}
+		if assert.Nil(t, json.Unmarshal([]byte(line), &o)) {
+			assert.Equal(t, true, o["passed"])
+			urls = append(urls, o["url"].(string))
+		}
+	}
+	assert.ElementsMatch(t, []string{ts.URL + "/a", ts.URL + "/b"}, urls)
+}
+
+func TestRun_Usage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"--format", "xml", "https://example.com/"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), `unknown format "xml"`)
+
+	stderr.Reset()
+	assert.Equal(t, exitUsage, run(context.Background(), []string{"--unknown"}, nil, &stdout, &stderr))
+	assert.Contains(t, stderr.String(), "Usage: urlverifier")
+
+	assert.Equal(t, exitPass, run(context.Background(), []string{"--help"}, nil, &stdout, &stderr))
+	assert.Empty(t, stdout.String())
+}
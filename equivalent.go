@@ -0,0 +1,180 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// EquivalenceLevel is how thoroughly Equivalent compares URLs. Each level
+// includes the ones before it.
+type EquivalenceLevel string
+
+const (
+	EquivalenceSyntactic  EquivalenceLevel = "syntactic"  // The URLs are the same string
+	EquivalenceNormalized EquivalenceLevel = "normalized" // The URLs are the same once normalized with Normalize
+	EquivalenceFinalURL   EquivalenceLevel = "final_url"  // The URLs lead to the same final URL after following redirects
+)
+
+// Component is a component of a URL
+type Component string
+
+const (
+	ComponentURL      Component = "url"      // The whole URL, if it could not be parsed
+	ComponentScheme   Component = "scheme"   // The scheme
+	ComponentUserinfo Component = "userinfo" // The username and password
+	ComponentHost     Component = "host"     // The host, without the port
+	ComponentPort     Component = "port"     // The port
+	ComponentPath     Component = "path"     // The path, or the opaque part of URLs without an authority
+	ComponentQuery    Component = "query"    // The query
+	ComponentFragment Component = "fragment" // The fragment
+)
+
+// Equivalence is the result of comparing two URLs with Equivalent
+type Equivalence struct {
+	Equivalent  bool             `json:"equivalent"`  // Whether the URLs are equivalent
+	Level       EquivalenceLevel `json:"level"`       // The level the URLs are equivalent at, or the level they were last compared at if they are not
+	A           string           `json:"a"`           // The first URL in the form it was last compared in, e.g. normalized
+	B           string           `json:"b"`           // The second URL in the form it was last compared in
+	Differences []Difference     `json:"differences"` // The components which differ, if the URLs are not equivalent
+}
+
+// Difference is a component which differs between two URLs
+type Difference struct {
+	Component Component `json:"component"` // The component which differs
+	A         string    `json:"a"`         // The component of the first URL
+	B         string    `json:"b"`         // The component of the second URL
+}
+
+// Equivalent reports whether two URLs refer to the same resource, comparing
+// them at each level up to the given one: as strings, then normalized with
+// Normalize using the normalize options of the verifier, then by the final
+// URLs of HTTP checks after following redirects. If they are not equivalent,
+// the components which differ at the last level are listed.
+func (v *Verifier) Equivalent(a, b string, level EquivalenceLevel) (*Equivalence, error) {
+	return v.EquivalentContext(context.Background(), a, b, level)
+}
+
+// EquivalentContext compares two URLs in the same way as Equivalent. The
+// context controls the HTTP checks of the final URL level, which are also
+// limited by the verifier timeout.
+func (v *Verifier) EquivalentContext(ctx context.Context, a, b string, level EquivalenceLevel) (*Equivalence, error) {
+	switch level {
+	case EquivalenceSyntactic, EquivalenceNormalized, EquivalenceFinalURL:
+	default:
+		return nil, fmt.Errorf("unknown equivalence level %q", level)
+	}
+
+	eq := compareURLs(EquivalenceSyntactic, a, b)
+	if eq.Equivalent || level == EquivalenceSyntactic {
+		return eq, nil
+	}
+
+	normalizedA, err := Normalize(a, v.normalizeOptions)
+	if err != nil {
+		return nil, &Error{Phase: PhaseParse, URL: a, Kind: ErrInvalidURL, Err: err}
+	}
+	normalizedB, err := Normalize(b, v.normalizeOptions)
+	if err != nil {
+		return nil, &Error{Phase: PhaseParse, URL: b, Kind: ErrInvalidURL, Err: err}
+	}
+
+	eq = compareURLs(EquivalenceNormalized, normalizedA, normalizedB)
+	if eq.Equivalent || level == EquivalenceNormalized {
+		return eq, nil
+	}
+
+	finalA, err := v.finalURL(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	finalB, err := v.finalURL(ctx, b)
+	if err != nil {
+		return nil, err
+	}
+
+	return compareURLs(EquivalenceFinalURL, finalA, finalB), nil
+}
+
+// finalURL returns the normalized final URL of a HTTP check of the URL
+func (v *Verifier) finalURL(ctx context.Context, rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", &Error{Phase: PhaseParse, URL: rawURL, Kind: ErrInvalidURL, Err: err}
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		err := fmt.Errorf("unable to follow the redirects of the URL: %w", ErrUnsupportedScheme)
+		return "", &Error{Phase: PhaseScheme, URL: rawURL, Kind: ErrUnsupportedScheme, Err: err}
+	}
+
+	ret, err := v.CheckHTTPContext(ctx, rawURL)
+	if err != nil {
+		return "", err
+	}
+	return Normalize(ret.FinalURL, v.normalizeOptions)
+}
+
+// compareURLs compares two URLs as strings, listing the components which
+// differ if they are not equal
+func compareURLs(level EquivalenceLevel, a, b string) *Equivalence {
+	eq := &Equivalence{Equivalent: a == b, Level: level, A: a, B: b}
+	if !eq.Equivalent {
+		eq.Differences = diffURLs(a, b)
+	}
+	return eq
+}
+
+// diffURLs returns the components which differ between two URLs
+func diffURLs(a, b string) []Difference {
+	ua, errA := url.Parse(a)
+	ub, errB := url.Parse(b)
+	if errA != nil || errB != nil {
+		return []Difference{{Component: ComponentURL, A: a, B: b}}
+	}
+
+	componentsA := urlComponents(ua)
+	componentsB := urlComponents(ub)
+
+	diffs := []Difference{}
+	for i, c := range componentsA {
+		if c.value != componentsB[i].value {
+			diffs = append(diffs, Difference{Component: c.component, A: c.value, B: componentsB[i].value})
+		}
+	}
+
+	// URLs can differ only in whether an empty query or fragment is present
+	if len(diffs) == 0 {
+		diffs = append(diffs, Difference{Component: ComponentURL, A: a, B: b})
+	}
+	return diffs
+}
+
+// urlComponent is the value of a component of a URL
+type urlComponent struct {
+	component Component
+	value     string
+}
+
+// urlComponents returns the components of a URL in the order they appear
+func urlComponents(u *url.URL) []urlComponent {
+	userinfo := ""
+	if u.User != nil {
+		userinfo = u.User.String()
+	}
+
+	path := u.EscapedPath()
+	if u.Opaque != "" {
+		path = u.Opaque
+	}
+
+	return []urlComponent{
+		{ComponentScheme, u.Scheme},
+		{ComponentUserinfo, userinfo},
+		{ComponentHost, u.Hostname()},
+		{ComponentPort, u.Port()},
+		{ComponentPath, path},
+		{ComponentQuery, u.RawQuery},
+		{ComponentFragment, u.EscapedFragment()},
+	}
+}
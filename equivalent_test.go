@@ -0,0 +1,107 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEquivalent_Syntactic(t *testing.T) {
+	verifier := NewVerifier()
+
+	eq, err := verifier.Equivalent("https://example.com/a", "https://example.com/a", EquivalenceSyntactic)
+	assert.Nil(t, err)
+	assert.Equal(t, &Equivalence{Equivalent: true, Level: EquivalenceSyntactic, A: "https://example.com/a", B: "https://example.com/a"}, eq)
+
+	eq, err = verifier.Equivalent("HTTPS://example.com:443/a?x=1", "https://example.com/a?x=2", EquivalenceSyntactic)
+	assert.Nil(t, err)
+	assert.False(t, eq.Equivalent)
+	assert.Equal(t, EquivalenceSyntactic, eq.Level)
+	assert.Equal(t, []Difference{
+		{Component: ComponentPort, A: "443", B: ""},
+		{Component: ComponentQuery, A: "x=1", B: "x=2"},
+	}, eq.Differences)
+}
+
+func TestEquivalent_Normalized(t *testing.T) {
+	verifier := NewVerifier()
+
+	eq, err := verifier.Equivalent("HTTP://Example.com:80/a/./b/../c?%7e", "http://example.com/a/c?~", EquivalenceNormalized)
+	assert.Nil(t, err)
+	assert.True(t, eq.Equivalent)
+	assert.Equal(t, EquivalenceNormalized, eq.Level)
+	assert.Equal(t, "http://example.com/a/c?~", eq.A)
+
+	eq, err = verifier.Equivalent("http://user@Example.com/a#top", "https://example.com:8443/b", EquivalenceNormalized)
+	assert.Nil(t, err)
+	assert.False(t, eq.Equivalent)
+	assert.Equal(t, EquivalenceNormalized, eq.Level)
+	assert.Equal(t, []Difference{
+		{Component: ComponentScheme, A: "http", B: "https"},
+		{Component: ComponentUserinfo, A: "user", B: ""},
+		{Component: ComponentPort, A: "", B: "8443"},
+		{Component: ComponentPath, A: "/a", B: "/b"},
+		{Component: ComponentFragment, A: "top", B: ""},
+	}, eq.Differences)
+
+	// Only an empty query differs
+	eq, err = verifier.Equivalent("http://example.com/?", "http://example.com/", EquivalenceNormalized)
+	assert.Nil(t, err)
+	assert.Equal(t, []Difference{{Component: ComponentURL, A: "http://example.com/?", B: "http://example.com/"}}, eq.Differences)
+
+	// The normalize options of the verifier are used
+	verifier = NewVerifier(WithNormalizeOptions(NormalizeOptions{StripWWW: true, DropFragment: true}))
+	eq, err = verifier.Equivalent("https://www.example.com/#top", "https://example.com/", EquivalenceNormalized)
+	assert.Nil(t, err)
+	assert.True(t, eq.Equivalent)
+
+	_, err = verifier.Equivalent("https://example.com/", "https://example.com/%zz", EquivalenceNormalized)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestEquivalent_FinalURL(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old", "/short":
+			http.Redirect(w, r, "/new", http.StatusMovedPermanently)
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+
+	eq, err := verifier.Equivalent(ts.URL+"/old", ts.URL+"/short", EquivalenceFinalURL)
+	assert.Nil(t, err)
+	assert.True(t, eq.Equivalent)
+	assert.Equal(t, EquivalenceFinalURL, eq.Level)
+	assert.Equal(t, ts.URL+"/new", eq.A)
+
+	eq, err = verifier.Equivalent(ts.URL+"/old", ts.URL+"/other", EquivalenceFinalURL)
+	assert.Nil(t, err)
+	assert.False(t, eq.Equivalent)
+	assert.Equal(t, EquivalenceFinalURL, eq.Level)
+	assert.Equal(t, []Difference{{Component: ComponentPath, A: "/new", B: "/other"}}, eq.Differences)
+
+	// The HTTP checks are not needed if the URLs are already equivalent
+	eq, err = verifier.Equivalent("https://example.unreachable/", "https://EXAMPLE.unreachable/", EquivalenceFinalURL)
+	assert.Nil(t, err)
+	assert.Equal(t, EquivalenceNormalized, eq.Level)
+}
+
+func TestEquivalent_FinalURLErrors(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	// The internal IP policy applies
+	_, err := NewVerifier().Equivalent(ts.URL+"/a", ts.URL+"/b", EquivalenceFinalURL)
+	assert.ErrorIs(t, err, ErrInternalIP)
+
+	_, err = NewVerifier().Equivalent("mailto:a@example.com", "mailto:b@example.com", EquivalenceFinalURL)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+
+	_, err = NewVerifier().Equivalent("https://example.com/", "https://example.org/", "unknown")
+	assert.EqualError(t, err, `unknown equivalence level "unknown"`)
+}
@@ -0,0 +1,201 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"sync"
+)
+
+// Sentinel errors describing why a verification failed. Use errors.Is to check
+// for them on errors returned by Verify and CheckHTTP.
+var (
+	ErrInvalidURL        = errors.New("the URL is invalid")
+	ErrUnsupportedScheme = errors.New("the URL does not have a HTTP or HTTPS scheme")
+	ErrInternalIP        = errors.New("the URL resolves to an internal IP")
+	ErrDeniedIP          = errors.New("the URL resolves to a denied IP")
+	ErrDNSFailure        = errors.New("the URL host could not be resolved")
+	ErrConnection        = errors.New("unable to connect to the URL")
+	ErrTLS               = errors.New("the TLS handshake failed")
+	ErrTimeout           = errors.New("the check timed out")
+	ErrCanceled          = errors.New("the check was canceled")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrRedirectLoop      = errors.New("redirect loop")
+	ErrUnknownTLD        = errors.New("the URL host does not have a known TLD")
+	ErrRateLimited       = errors.New("the rate limit delays the check past its deadline")
+)
+
+// Phase is the phase of a verification in which an error occurred
+type Phase string
+
+const (
+	PhaseParse    Phase = "parse"    // Parsing the URL
+	PhaseScheme   Phase = "scheme"   // Checking the URL has a HTTP or HTTPS scheme
+	PhaseDNS      Phase = "dns"      // Resolving the host
+	PhaseIPCheck  Phase = "ip_check" // Checking the IPs the host resolves to against the IP policy
+	PhaseConnect  Phase = "connect"  // Connecting to the host
+	PhaseTLS      Phase = "tls"      // The TLS handshake
+	PhaseHTTP     Phase = "http"     // Sending the request and reading the response
+	PhaseRedirect Phase = "redirect" // Following a redirect
+)
+
+// Error is returned by Verify and CheckHTTP when verification fails. Kind is
+// one of the sentinel errors and is matched by errors.Is, while Err is the
+// underlying error, e.g. a *url.Error or *net.DNSError, available through
+// errors.As.
+type Error struct {
+	Phase Phase  // The phase in which the error occurred
+	URL   string // The URL being checked when the error occurred
+	Host  string // The host being checked when the error occurred
+	IP    net.IP // The IP being checked or connected to, if known
+	Kind  error  // The sentinel error describing the failure
+	Err   error  // The underlying error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the kind of the error
+func (e *Error) Is(target error) bool {
+	return e.Kind != nil && target == e.Kind
+}
+
+// RedirectError is returned when the HTTP check refuses to follow a redirect
+type RedirectError struct {
+	Hop int    // The number of the redirect that was refused, starting at 1
+	URL string // The URL the redirect pointed to
+	Err error  // The reason the redirect was refused
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("refused to follow redirect %d to %s: %s", e.Hop, e.URL, e.Err)
+}
+
+func (e *RedirectError) Unwrap() error {
+	return e.Err
+}
+
+// errorKind returns the sentinel error describing err
+func errorKind(err error) error {
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	var unknownAuthorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var certInvalidErr x509.CertificateInvalidError
+	var recordHeaderErr tls.RecordHeaderError
+
+	for _, kind := range []error{ErrInternalIP, ErrDeniedIP, ErrUnsupportedScheme, ErrTooManyRedirects, ErrRedirectLoop, ErrInvalidURL, ErrUnknownTLD, ErrRateLimited} {
+		if errors.Is(err, kind) {
+			return kind
+		}
+	}
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return ErrCanceled
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return ErrTimeout
+	case errors.As(err, &dnsErr):
+		return ErrDNSFailure
+	case errors.As(err, &unknownAuthorityErr), errors.As(err, &hostnameErr),
+		errors.As(err, &certInvalidErr), errors.As(err, &recordHeaderErr):
+		return ErrTLS
+	}
+	return ErrConnection
+}
+
+// newError wraps err in an *Error, unless it already is one. The phase is
+// derived from the error where possible, falling back to the given phase.
+func newError(phase Phase, rawURL, host string, ip net.IP, err error) error {
+	var verifyErr *Error
+	if errors.As(err, &verifyErr) {
+		return err
+	}
+
+	kind := errorKind(err)
+
+	var redirectErr *RedirectError
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(err, &redirectErr):
+		phase = PhaseRedirect
+		rawURL = redirectErr.URL
+	case kind == ErrInternalIP || kind == ErrDeniedIP:
+		phase = PhaseIPCheck
+	case kind == ErrDNSFailure || errors.As(err, &dnsErr):
+		phase = PhaseDNS
+	case kind == ErrTLS:
+		phase = PhaseTLS
+	}
+
+	if refusal := refusalFromError(err); refusal != nil {
+		host = refusal.Host
+		ip = refusal.IP
+	}
+
+	return &Error{Phase: phase, URL: rawURL, Host: host, IP: ip, Kind: kind, Err: err}
+}
+
+// phaseTracker records the phase of a HTTP check and the IP connected to, so
+// errors can report where they occurred
+type phaseTracker struct {
+	mu    sync.Mutex
+	phase Phase
+	ip    net.IP
+	inDNS bool // Whether a DNS lookup is in progress, whose connections to DNS servers are ignored
+}
+
+func (p *phaseTracker) set(phase Phase, addr string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inDNS && phase == PhaseConnect {
+		return
+	}
+
+	p.phase = phase
+	p.inDNS = phase == PhaseDNS
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := net.ParseIP(host); ip != nil {
+			p.ip = ip
+		}
+	}
+}
+
+func (p *phaseTracker) get() (Phase, net.IP) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.phase, p.ip
+}
+
+// trace returns a ClientTrace which updates the tracker
+func (p *phaseTracker) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			p.set(PhaseDNS, "")
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			p.set(PhaseIPCheck, "")
+		},
+		ConnectStart: func(network, addr string) {
+			p.set(PhaseConnect, addr)
+		},
+		TLSHandshakeStart: func() {
+			p.set(PhaseTLS, "")
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.set(PhaseHTTP, info.Conn.RemoteAddr().String())
+		},
+	}
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKind(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind error
+	}{
+		{err: &refusedIPError{Refusal{Category: IPCategoryLoopback}}, kind: ErrInternalIP},
+		{err: &refusedIPError{Refusal{CIDR: "198.51.100.0/24"}}, kind: ErrDeniedIP},
+		{err: &RedirectError{Err: ErrUnsupportedScheme}, kind: ErrUnsupportedScheme},
+		{err: &net.DNSError{Err: "no such host", Name: "example.unreachable", IsNotFound: true}, kind: ErrDNSFailure},
+		{err: &net.DNSError{Err: "i/o timeout", Name: "example.com", IsTimeout: true}, kind: ErrTimeout},
+		{err: &url.Error{Op: "Get", URL: "http://example.com/", Err: context.DeadlineExceeded}, kind: ErrTimeout},
+		{err: &url.Error{Op: "Get", URL: "http://example.com/", Err: context.Canceled}, kind: ErrCanceled},
+		{err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, kind: ErrConnection},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.kind, errorKind(test.err), test.err.Error())
+	}
+}
+
+func TestError_IsAndAs(t *testing.T) {
+	underlying := &net.DNSError{Err: "no such host", Name: "example.unreachable", IsNotFound: true}
+	err := newError(PhaseConnect, "http://example.unreachable/", "example.unreachable", nil, fmt.Errorf("wrapped: %w", underlying))
+
+	var verifyErr *Error
+	var dnsErr *net.DNSError
+	assert.ErrorAs(t, err, &verifyErr)
+	assert.ErrorAs(t, err, &dnsErr)
+	assert.ErrorIs(t, err, ErrDNSFailure)
+	assert.NotErrorIs(t, err, ErrTimeout)
+	assert.Equal(t, PhaseDNS, verifyErr.Phase)
+	assert.Equal(t, "example.unreachable", verifyErr.Host)
+	assert.Equal(t, "wrapped: lookup example.unreachable: no such host", err.Error())
+
+	// Errors are not wrapped twice
+	assert.Same(t, verifyErr, newError(PhaseHTTP, "", "", nil, err))
+}
+
+func TestCheckVerify_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		resolver Resolver
+		opts     []Option
+		kind     error
+		phase    Phase
+		host     string
+		ip       string
+	}{
+		{name: "unsupported scheme", url: "ftp://example.com/", kind: ErrUnsupportedScheme, phase: PhaseScheme},
+		{name: "dns failure", url: "https://example.unreachable/", resolver: notFoundResolver{}, kind: ErrDNSFailure, phase: PhaseDNS, host: "example.unreachable"},
+		{name: "internal ip", url: "https://internal.example/", resolver: &stubResolver{responses: [][]string{{"10.0.0.5"}}}, kind: ErrInternalIP, phase: PhaseIPCheck, host: "internal.example", ip: "10.0.0.5"},
+		{name: "denied ip", url: "https://partner.example/", resolver: &stubResolver{responses: [][]string{{"198.51.100.7"}}}, opts: []Option{WithDeniedCIDRs(netip.MustParsePrefix("198.51.100.0/24"))}, kind: ErrDeniedIP, phase: PhaseIPCheck, host: "partner.example", ip: "198.51.100.7"},
+		{name: "dns timeout", url: "https://slow.example/", resolver: blockingResolver{}, opts: []Option{WithTimeout(50 * time.Millisecond)}, kind: ErrTimeout, phase: PhaseDNS, host: "slow.example"},
+	}
+
+	for _, test := range tests {
+		opts := append([]Option{WithHTTPCheck()}, test.opts...)
+		if test.resolver != nil {
+			opts = append(opts, WithResolver(test.resolver))
+		}
+
+		verifier := NewVerifier(opts...)
+		_, err := verifier.Verify(test.url)
+
+		var verifyErr *Error
+		if !assert.ErrorAs(t, err, &verifyErr, test.name) {
+			continue
+		}
+		assert.ErrorIs(t, err, test.kind, test.name)
+		assert.Equal(t, test.phase, verifyErr.Phase, test.name)
+		assert.Equal(t, test.url, verifyErr.URL, test.name)
+		assert.Equal(t, test.host, verifyErr.Host, test.name)
+		if test.ip != "" {
+			assert.Equal(t, net.ParseIP(test.ip), verifyErr.IP, test.name)
+		}
+	}
+}
+
+func TestCheckHTTP_Errors(t *testing.T) {
+	refused := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	refusedURL := refused.URL
+	refused.Close()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.0.0.5/admin", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer tlsServer.Close()
+
+	tests := []struct {
+		name  string
+		url   string
+		opts  []Option
+		kind  error
+		phase Phase
+	}{
+		{name: "connection refused", url: refusedURL, opts: []Option{WithHTTPCheckInternal()}, kind: ErrConnection, phase: PhaseConnect},
+		{name: "internal ip", url: refusedURL, kind: ErrInternalIP, phase: PhaseIPCheck},
+		{name: "redirect to internal ip", url: ts.URL, opts: []Option{WithAllowedCIDRs(netip.MustParsePrefix("127.0.0.1/32"))}, kind: ErrInternalIP, phase: PhaseRedirect},
+		{name: "unknown authority", url: tlsServer.URL, opts: []Option{WithHTTPCheckInternal()}, kind: ErrTLS, phase: PhaseTLS},
+		{name: "invalid url", url: "http://[::1", kind: ErrInvalidURL, phase: PhaseParse},
+		{name: "proxy error", url: "http://public.example/", opts: []Option{WithTransport(&http.Transport{Proxy: func(*http.Request) (*url.URL, error) {
+			return nil, errors.New("invalid proxy")
+		}})}, kind: ErrConnection, phase: PhaseConnect},
+	}
+
+	for _, test := range tests {
+		verifier := NewVerifier(test.opts...)
+		_, err := verifier.CheckHTTP(test.url)
+
+		var verifyErr *Error
+		if !assert.ErrorAs(t, err, &verifyErr, test.name) {
+			continue
+		}
+		assert.ErrorIs(t, err, test.kind, test.name)
+		assert.Equal(t, test.phase, verifyErr.Phase, test.name)
+	}
+}
@@ -2,44 +2,71 @@
 package urlverifier
 
 import (
-	"crypto/tls"
+	"context"
+	"io"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// MethodStrategy is the HTTP method strategy of the HTTP check
+type MethodStrategy string
+
+const (
+	MethodGET         MethodStrategy = "get"           // Send a GET request
+	MethodHEAD        MethodStrategy = "head"          // Send a HEAD request
+	MethodHEADThenGET MethodStrategy = "head_then_get" // Send a HEAD request, falling back to GET if the server does not support HEAD
 )
 
 // HTTP is the result of a HTTP check
 type HTTP struct {
-	Reachable  bool `json:"reachable"`   // Whether the URL is reachable via HTTP. This may be true even if the response is an HTTP error e.g. a 500 error.
-	StatusCode int  `json:"status_code"` // The HTTP status code
-	IsSuccess  bool `josn:"is_success"`  // Whether the HTTP response is a success (2xx) or success-like code (3xx)
+	Reachable  bool      `json:"reachable"`   // Whether the URL is reachable via HTTP. This may be true even if the response is an HTTP error e.g. a 500 error.
+	StatusCode int       `json:"status_code"` // The HTTP status code
+	IsSuccess  bool      `josn:"is_success"`  // Whether the HTTP response is a success (2xx) or success-like code (3xx)
+	FinalURL   string    `json:"final_url"`   // The URL of the final response, after following redirects
+	Redirects  []Hop     `json:"redirects"`   // The redirects followed, in order
+	Method     string    `json:"method"`      // The HTTP method of the request, e.g. HEAD or GET
+	TLS        *TLS      `json:"tls"`         // The TLS connection and certificate of the final response, or of the failed handshake, if HTTPS
+	Timing     *Timing   `json:"timing"`      // The duration of each phase of the check, summed over all attempts
+	Attempts   []Attempt `json:"attempts"`    // The outcome of each attempt, if a retry policy is set
 }
 
 // CheckHTTP checks if the URL is reachable via HTTP
 func (v *Verifier) CheckHTTP(urlToCheck string) (*HTTP, error) {
+	return v.CheckHTTPContext(context.Background(), urlToCheck)
+}
+
+// CheckHTTPContext checks if the URL is reachable via HTTP. The context controls
+// the DNS lookup, dialing, TLS handshake and reading the response, which are
+// also limited by the verifier timeout.
+func (v *Verifier) CheckHTTPContext(ctx context.Context, urlToCheck string) (*HTTP, error) {
+	ctx, cancel := v.withTimeout(ctx)
+	defer cancel()
+
 	ret := HTTP{
 		Reachable: false,
 		IsSuccess: false,
 	}
 
-	// Create client for skipping certificate verification
-	var client http.Client
-
-	if v.skipCertVerification {
-		client = http.Client{
-			Transport: &http.Transport{
-				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
-			},
-		}
-	}
+	timer := newTimingRecorder()
+	ctx = httptrace.WithClientTrace(ctx, timer.trace())
 
 	// Check if the URL is reachable via HTTP
-	resp, err := client.Get(urlToCheck)
+	resp, err := v.attemptWithRetries(ctx, &ret, urlToCheck)
+	ret.Timing = timer.finish()
 	if err != nil {
 		return &ret, err
 	}
-	defer resp.Body.Close()
+	defer drainBody(resp.Body, v.maxBodyBytes)
 
 	ret.Reachable = true
 	ret.StatusCode = resp.StatusCode
+	ret.FinalURL = resp.Request.URL.Redacted()
+	if resp.TLS != nil {
+		ret.TLS = v.inspectTLS(resp.TLS, resp.Request.URL.Hostname())
+	}
 
 	// Check if the HTTP response is a success (2xx) or success-like code (3xx)
 	if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusBadRequest {
@@ -48,3 +75,93 @@ func (v *Verifier) CheckHTTP(urlToCheck string) (*HTTP, error) {
 
 	return &ret, nil
 }
+
+// attempt sends a request for the URL, falling back from HEAD to GET if the
+// method strategy allows it and the server does not support HEAD
+func (v *Verifier) attempt(ctx context.Context, ret *HTTP, urlToCheck string) (*http.Response, error) {
+	method := v.method(urlToCheck)
+
+	resp, err := v.send(ctx, ret, method, urlToCheck)
+	if err != nil {
+		return nil, err
+	}
+
+	if method == http.MethodHead && v.methodStrategy == MethodHEADThenGET &&
+		(resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented) {
+		drainBody(resp.Body, 0)
+		return v.send(ctx, ret, http.MethodGet, urlToCheck)
+	}
+	return resp, nil
+}
+
+// send sends a request for the URL with the HTTP check client, recording the
+// method, redirects followed and any failed TLS handshake on ret
+func (v *Verifier) send(ctx context.Context, ret *HTTP, method, urlToCheck string) (*http.Response, error) {
+	client := v.httpCheckClient()
+
+	// Failures before the first trace event, e.g. from the rate limiter or a
+	// proxy, are reported as connecting as the host may not have been resolved
+	tracker := &phaseTracker{phase: PhaseConnect}
+	ctx = httptrace.WithClientTrace(ctx, tracker.trace())
+
+	tlsRecorder := &tlsRecorder{}
+	ctx = httptrace.WithClientTrace(ctx, tlsRecorder.trace())
+
+	recorder := &redirectRecorder{start: time.Now()}
+	ctx = context.WithValue(ctx, redirectRecorderKey{}, recorder)
+
+	req, err := http.NewRequestWithContext(ctx, method, urlToCheck, nil)
+	if err != nil {
+		return nil, &Error{Phase: PhaseParse, URL: urlToCheck, Kind: ErrInvalidURL, Err: err}
+	}
+	if v.userAgent != "" {
+		req.Header.Set("User-Agent", v.userAgent)
+	}
+
+	ret.Method = method
+	resp, err := client.Do(req)
+	ret.Redirects = recorder.hops
+	if err != nil {
+		ret.TLS = inspectTLSError(tlsRecorder.get(), req.URL.Hostname(), err)
+		phase, ip := tracker.get()
+		return nil, newError(phase, urlToCheck, req.URL.Hostname(), ip, err)
+	}
+	return resp, nil
+}
+
+// method returns the HTTP method of the first request for the URL, according
+// to the method strategy
+func (v *Verifier) method(urlToCheck string) string {
+	switch v.methodStrategy {
+	case MethodHEAD:
+		return http.MethodHead
+	case MethodHEADThenGET:
+		if u, err := url.Parse(urlToCheck); err == nil && v.isGETOnlyHost(u.Hostname()) {
+			return http.MethodGet
+		}
+		return http.MethodHead
+	}
+	return http.MethodGet
+}
+
+// isGETOnlyHost reports whether the host, or a domain it is a subdomain of, is
+// known not to support HEAD requests
+func (v *Verifier) isGETOnlyHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for _, h := range v.getOnlyHosts {
+		h = strings.ToLower(strings.TrimSuffix(h, "."))
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// drainBody reads up to limit bytes of the body and closes it. Fully read
+// bodies allow the transport to reuse the connection.
+func drainBody(body io.ReadCloser, limit int64) {
+	if limit > 0 {
+		_, _ = io.Copy(io.Discard, io.LimitReader(body, limit))
+	}
+	body.Close()
+}
@@ -2,12 +2,31 @@
 package urlverifier
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
+// withoutTiming returns a copy of the result without its timing, which varies
+// between runs, for comparison with an expected result
+func withoutTiming(ret *HTTP) *HTTP {
+	if ret == nil {
+		return nil
+	}
+	cp := *ret
+	cp.Timing = nil
+	return &cp
+}
+
 func TestCheckHTTP_Status200(t *testing.T) {
 	urlToCheck := "http://example.com/"
 
@@ -18,9 +37,12 @@ func TestCheckHTTP_Status200(t *testing.T) {
 		Reachable:  true,
 		StatusCode: 200,
 		IsSuccess:  true,
+		FinalURL:   urlToCheck,
+		Method:     "GET",
 	}
 
-	assert.Equal(t, expected, ret)
+	assert.Equal(t, expected, withoutTiming(ret))
+	assert.Nil(t, ret.TLS)
 	assert.Nil(t, err)
 }
 
@@ -34,25 +56,30 @@ func TestCheckHTTP_Status404(t *testing.T) {
 		Reachable:  true,
 		StatusCode: 404,
 		IsSuccess:  false,
+		FinalURL:   urlToCheck,
+		Method:     "GET",
 	}
 
-	assert.Equal(t, expected, ret)
+	assert.Equal(t, expected, withoutTiming(ret))
 	assert.Nil(t, err)
 }
 
 func TestCheckHTTP_Unreachable(t *testing.T) {
 	urlToCheck := "http://example.unreachable"
 
-	verifier := NewVerifier()
+	verifier := NewVerifier(WithResolver(notFoundResolver{}))
 	ret, err := verifier.CheckHTTP(urlToCheck)
 
 	expected := &HTTP{
 		Reachable: false,
 		IsSuccess: false,
+		Method:    "GET",
 	}
 
-	assert.Equal(t, expected, ret)
-	assert.IsType(t, &url.Error{}, err)
+	var urlErr *url.Error
+	assert.Equal(t, expected, withoutTiming(ret))
+	assert.ErrorAs(t, err, &urlErr)
+	assert.ErrorIs(t, err, ErrDNSFailure)
 	assert.ErrorContains(t, err, "lookup example.unreachable: no such host")
 }
 
@@ -62,13 +89,13 @@ func TestCheckHTTP_expiredCert(t *testing.T) {
 	verifier := NewVerifier()
 	ret, err := verifier.CheckHTTP(urlToCheck)
 
-	expected := &HTTP{
-		Reachable: false,
-		IsSuccess: false,
+	var urlErr *url.Error
+	assert.False(t, ret.Reachable)
+	if assert.NotNil(t, ret.TLS) {
+		assert.False(t, ret.TLS.ChainValid)
 	}
-
-	assert.Equal(t, expected, ret)
-	assert.IsType(t, &url.Error{}, err)
+	assert.ErrorAs(t, err, &urlErr)
+	assert.ErrorIs(t, err, ErrTLS)
 	assert.ErrorContains(t, err, "x509:")
 }
 
@@ -83,8 +110,158 @@ func TestCheckHTTP_expiredCert_allowSkip(t *testing.T) {
 		Reachable:  true,
 		StatusCode: 200,
 		IsSuccess:  true,
+		FinalURL:   urlToCheck,
+		Method:     "GET",
 	}
 
-	assert.Equal(t, expected, ret)
+	assert.Equal(t, expected, withoutTiming(ret))
 	assert.Nil(t, err)
 }
+
+func TestCheckHTTPContext_Timeout(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier()
+	verifier.AllowHTTPCheckInternal()
+	verifier.SetTimeout(50 * time.Millisecond)
+	ret, err := verifier.CheckHTTPContext(context.Background(), ts.URL)
+
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.ErrorIs(t, err, ErrTimeout)
+}
+
+func TestCheckHTTPContext_Cancelled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	verifier := NewVerifier()
+	verifier.AllowHTTPCheckInternal()
+	ret, err := verifier.CheckHTTPContext(ctx, ts.URL)
+
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.ErrorIs(t, err, ErrCanceled)
+}
+
+func TestCheckHTTP_MethodHEAD(t *testing.T) {
+	method := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithMethodStrategy(MethodHEAD))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "HEAD", FinalURL: ts.URL}, withoutTiming(ret))
+	assert.Equal(t, http.MethodHead, method)
+}
+
+func TestCheckHTTP_MethodHEADThenGET(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		methods []string
+	}{
+		{"HEAD supported", http.StatusOK, []string{"HEAD"}},
+		{"HEAD not found", http.StatusNotFound, []string{"HEAD"}},
+		{"method not allowed", http.StatusMethodNotAllowed, []string{"HEAD", "GET"}},
+		{"not implemented", http.StatusNotImplemented, []string{"HEAD", "GET"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			methods := []string{}
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				methods = append(methods, r.Method)
+				if r.Method == http.MethodHead {
+					w.WriteHeader(tt.status)
+				}
+			}))
+			defer ts.Close()
+
+			verifier := NewVerifier(WithHTTPCheckInternal(), WithMethodStrategy(MethodHEADThenGET))
+			ret, err := verifier.CheckHTTP(ts.URL)
+
+			assert.Nil(t, err)
+			assert.Equal(t, tt.methods, methods)
+			assert.Equal(t, tt.methods[len(tt.methods)-1], ret.Method)
+		})
+	}
+}
+
+func TestCheckHTTP_GETOnlyHosts(t *testing.T) {
+	method := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method = r.Method
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewVerifier(
+		WithHTTPCheckInternal(),
+		WithMethodStrategy(MethodHEADThenGET),
+		WithGETOnlyHosts("example.com"),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+	)
+
+	ret, err := verifier.CheckHTTP(fmt.Sprintf("http://files.example.com:%s/", tsURL.Port()))
+	assert.Nil(t, err)
+	assert.Equal(t, "GET", ret.Method)
+	assert.Equal(t, http.MethodGet, method)
+
+	ret, err = verifier.CheckHTTP(fmt.Sprintf("http://notexample.com:%s/", tsURL.Port()))
+	assert.Nil(t, err)
+	assert.Equal(t, "HEAD", ret.Method)
+	assert.Equal(t, http.MethodHead, method)
+}
+
+func TestCheckHTTP_MaxBodyBytes(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(make([]byte, 1<<20))
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	// Bodies larger than the limit are not read, so connections are not reused
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithMaxBodyBytes(1024))
+	defer verifier.Close()
+
+	for i := 0; i < 2; i++ {
+		ret, err := verifier.CheckHTTP(ts.URL)
+		assert.Nil(t, err)
+		assert.True(t, ret.IsSuccess)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conns))
+
+	// Bodies within the limit are read, so connections are reused
+	verifier = NewVerifier(WithHTTPCheckInternal(), WithMaxBodyBytes(2<<20))
+	defer verifier.Close()
+
+	for i := 0; i < 2; i++ {
+		ret, err := verifier.CheckHTTP(ts.URL)
+		assert.Nil(t, err)
+		assert.True(t, ret.IsSuccess)
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&conns))
+}
@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT
+
+// Package ratelimit provides the token bucket rate limiter shared by the
+// verifier, which limits HTTP checks per host, and the JSON API server, which
+// limits requests per client.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrDeadline is returned by Wait when the wait would exceed the deadline of
+// the context
+var ErrDeadline = errors.New("ratelimit: the wait would exceed the context deadline")
+
+// maxIdleBuckets is the number of buckets kept by the limiter before buckets
+// which are full, and so equivalent to a new bucket, are removed
+const maxIdleBuckets = 1024
+
+// Limiter is a token bucket rate limiter with a bucket per key, e.g. per host
+// or per client. It is safe for concurrent use.
+type Limiter struct {
+	rate  float64 // Tokens added to each bucket per second
+	burst float64 // The maximum number of tokens in a bucket
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+}
+
+// bucket is the token bucket of a key
+type bucket struct {
+	tokens  float64   // The number of tokens, negative if requests are waiting for tokens
+	last    time.Time // When tokens were last added
+	blocked time.Time // When requests may be sent again after a Retry-After response
+}
+
+// New creates a limiter adding rps tokens per second to each bucket, up to
+// burst tokens
+func New(rps float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{rate: rps, burst: float64(burst), buckets: map[string]*bucket{}}
+}
+
+// Wait waits until a request may be sent for the key, or ctx is done. If the
+// request may not be sent before the deadline of ctx, it returns ErrDeadline
+// without waiting.
+func (l *Limiter) Wait(ctx context.Context, key string) error {
+	now := time.Now()
+	delay := l.Reserve(key, now)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		l.cancel(key)
+		return fmt.Errorf("%w: the wait is %s", ErrDeadline, delay.Round(time.Millisecond))
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		l.cancel(key)
+		return ctx.Err()
+	}
+}
+
+// Reserve takes a token from the bucket of the key, returning how long to wait
+// until the request may be sent
+func (l *Limiter) Reserve(key string, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, now)
+	b.tokens--
+	var delay time.Duration
+	if b.tokens < 0 {
+		delay = time.Duration(-b.tokens / l.rate * float64(time.Second))
+	}
+	if blocked := b.blocked.Sub(now); blocked > delay {
+		delay = blocked
+	}
+	return delay
+}
+
+// Take takes n tokens, or the whole burst if n is larger, from the bucket of
+// the key. If there are not enough tokens, none are taken and it returns how
+// long until there will be.
+func (l *Limiter) Take(key string, n int, now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, now)
+	cost := float64(n)
+	if cost > l.burst {
+		cost = l.burst
+	}
+	if b.tokens < cost {
+		return time.Duration((cost - b.tokens) / l.rate * float64(time.Second))
+	}
+	b.tokens -= cost
+	return 0
+}
+
+// Block stops requests for the key until the given time, e.g. after a
+// response with a Retry-After header
+func (l *Limiter) Block(key string, until time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(key, time.Now())
+	if until.After(b.blocked) {
+		b.blocked = until
+	}
+}
+
+// Keys returns the sorted keys of the buckets kept by the limiter
+func (l *Limiter) Keys() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := make([]string, 0, len(l.buckets))
+	for key := range l.buckets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// cancel returns the token of a request which was not sent
+func (l *Limiter) cancel(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens++
+	}
+}
+
+// bucket returns the bucket of the key, refilled up to now, creating it if
+// needed. l.mu must be held.
+func (l *Limiter) bucket(key string, now time.Time) *bucket {
+	if len(l.buckets) > maxIdleBuckets {
+		l.prune(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	l.refill(b, now)
+	return b
+}
+
+// refill adds the tokens accrued since the bucket was last refilled
+func (l *Limiter) refill(b *bucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * l.rate
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+		b.last = now
+	}
+}
+
+// prune removes buckets which are full and not blocked
+func (l *Limiter) prune(now time.Time) {
+	for key, b := range l.buckets {
+		l.refill(b, now)
+		if b.tokens >= l.burst && !b.blocked.After(now) {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: MIT
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Reserve(t *testing.T) {
+	limiter := New(10, 2)
+	now := time.Now()
+
+	// The burst is allowed at once, then requests are spaced by the rate
+	assert.Equal(t, time.Duration(0), limiter.Reserve("a.example", now))
+	assert.Equal(t, time.Duration(0), limiter.Reserve("a.example", now))
+	assert.Equal(t, 100*time.Millisecond, limiter.Reserve("a.example", now))
+	assert.Equal(t, 200*time.Millisecond, limiter.Reserve("a.example", now))
+
+	// Other keys have their own bucket
+	assert.Equal(t, time.Duration(0), limiter.Reserve("b.example", now))
+
+	// Tokens are added over time, up to the burst
+	assert.Equal(t, time.Duration(0), limiter.Reserve("a.example", now.Add(time.Second)))
+	assert.Equal(t, time.Duration(0), limiter.Reserve("b.example", now.Add(time.Hour)))
+	assert.Equal(t, time.Duration(0), limiter.Reserve("b.example", now.Add(time.Hour)))
+	assert.Equal(t, 100*time.Millisecond, limiter.Reserve("b.example", now.Add(time.Hour)))
+
+	assert.Equal(t, []string{"a.example", "b.example"}, limiter.Keys())
+}
+
+func TestLimiter_Take(t *testing.T) {
+	now := time.Now()
+	limiter := New(2, 4)
+
+	assert.Equal(t, time.Duration(0), limiter.Take("a", 3, now))
+	assert.Equal(t, 1*time.Second, limiter.Take("a", 3, now))
+
+	// Requests costing more than the burst take the whole burst
+	assert.Equal(t, 1500*time.Millisecond, limiter.Take("a", 10, now))
+	assert.Equal(t, time.Duration(0), limiter.Take("a", 10, now.Add(1500*time.Millisecond)))
+}
+
+func TestLimiter_Block(t *testing.T) {
+	limiter := New(10, 1)
+	now := time.Now()
+
+	limiter.Block("a.example", now.Add(time.Minute))
+	assert.Equal(t, time.Minute, limiter.Reserve("a.example", now))
+
+	// An earlier block does not shorten the wait
+	limiter.Block("a.example", now.Add(time.Second))
+	assert.Equal(t, time.Minute-time.Second, limiter.Reserve("a.example", now.Add(time.Second)))
+}
+
+func TestLimiter_WaitCanceled(t *testing.T) {
+	limiter := New(1, 1)
+	limiter.Block("a.example", time.Now().Add(time.Minute))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(10*time.Millisecond, cancel)
+
+	assert.ErrorIs(t, limiter.Wait(ctx, "a.example"), context.Canceled)
+}
+
+func TestLimiter_WaitPastDeadline(t *testing.T) {
+	limiter := New(1, 1)
+	limiter.Block("a.example", time.Now().Add(time.Minute))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// The wait is beyond the deadline, so it returns without waiting
+	start := time.Now()
+	assert.ErrorIs(t, limiter.Wait(ctx, "a.example"), ErrDeadline)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	// The token is returned
+	assert.Equal(t, time.Duration(0), limiter.Reserve("a.example", time.Now().Add(time.Minute)))
+}
+
+func TestLimiter_Prune(t *testing.T) {
+	limiter := New(1, 1)
+	now := time.Now()
+
+	limiter.Block("blocked", now.Add(time.Hour))
+	for i := 0; i <= maxIdleBuckets; i++ {
+		limiter.Reserve(fmt.Sprintf("%d.example", i), now)
+	}
+
+	// Once the limit is passed, full buckets are removed but blocked ones kept
+	limiter.Reserve("new", now.Add(time.Minute))
+	assert.Equal(t, []string{"blocked", "new"}, limiter.Keys())
+}
@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"net"
+	"net/netip"
+)
+
+// IPCategory is the category of a special-purpose IP range which HTTP checks
+// are refused to unless internal checks are allowed. The ranges are based on
+// the IANA IPv4 and IPv6 Special-Purpose Address Registries, plus cloud
+// metadata endpoints.
+type IPCategory string
+
+const (
+	IPCategoryNone           IPCategory = ""                // Not a special-purpose IP
+	IPCategoryUnspecified    IPCategory = "unspecified"     // 0.0.0.0/32, ::/128
+	IPCategoryThisNetwork    IPCategory = "this_network"    // 0.0.0.0/8
+	IPCategoryLoopback       IPCategory = "loopback"        // 127.0.0.0/8, ::1/128
+	IPCategoryPrivate        IPCategory = "private"         // RFC 1918 private networks
+	IPCategoryCGNAT          IPCategory = "cgnat"           // 100.64.0.0/10 shared address space
+	IPCategoryLinkLocal      IPCategory = "link_local"      // 169.254.0.0/16, fe80::/10
+	IPCategoryMetadata       IPCategory = "metadata"        // Cloud metadata endpoints, e.g. 169.254.169.254
+	IPCategoryIETFProtocol   IPCategory = "ietf_protocol"   // 192.0.0.0/24, 2001::/23 IETF protocol assignments
+	IPCategoryDocumentation  IPCategory = "documentation"   // TEST-NET-1/2/3, 2001:db8::/32, 3fff::/20
+	IPCategoryBenchmarking   IPCategory = "benchmarking"    // 198.18.0.0/15, 2001:2::/48
+	IPCategoryDeprecated     IPCategory = "deprecated"      // 192.88.99.0/24 6to4 relay anycast, fec0::/10 site-local
+	IPCategoryReserved       IPCategory = "reserved"        // 240.0.0.0/4, ::/8
+	IPCategoryBroadcast      IPCategory = "broadcast"       // 255.255.255.255/32
+	IPCategoryMulticast      IPCategory = "multicast"       // 224.0.0.0/4, ff00::/8
+	IPCategoryNAT64          IPCategory = "nat64"           // 64:ff9b::/96, 64:ff9b:1::/48
+	IPCategoryDiscardOnly    IPCategory = "discard_only"    // 100::/64
+	IPCategoryUniqueLocal    IPCategory = "unique_local"    // fc00::/7
+	IPCategoryTeredo         IPCategory = "teredo"          // 2001::/32
+	IPCategory6to4           IPCategory = "6to4"            // 2002::/16
+	IPCategorySegmentRouting IPCategory = "segment_routing" // 5f00::/16
+)
+
+// specialPurposeRange is a range of IPs and its category
+type specialPurposeRange struct {
+	prefix   netip.Prefix
+	category IPCategory
+}
+
+// specialPurposeRanges are checked in order, so more specific ranges must come
+// before the ranges containing them. Ranges with IPCategoryNone are globally
+// reachable exceptions within a special-purpose range.
+var specialPurposeRanges = []specialPurposeRange{
+	// Cloud metadata endpoints
+	{netip.MustParsePrefix("169.254.169.254/32"), IPCategoryMetadata}, // AWS, GCP, Azure, OpenStack and others
+	{netip.MustParsePrefix("169.254.170.2/32"), IPCategoryMetadata},   // AWS ECS task metadata
+	{netip.MustParsePrefix("100.100.100.200/32"), IPCategoryMetadata}, // Alibaba Cloud
+	{netip.MustParsePrefix("168.63.129.16/32"), IPCategoryMetadata},   // Azure WireServer
+	{netip.MustParsePrefix("fd00:ec2::254/128"), IPCategoryMetadata},  // AWS IPv6
+
+	// IPv4 Special-Purpose Address Registry
+	{netip.MustParsePrefix("0.0.0.0/32"), IPCategoryUnspecified},
+	{netip.MustParsePrefix("0.0.0.0/8"), IPCategoryThisNetwork},
+	{netip.MustParsePrefix("10.0.0.0/8"), IPCategoryPrivate},
+	{netip.MustParsePrefix("100.64.0.0/10"), IPCategoryCGNAT},
+	{netip.MustParsePrefix("127.0.0.0/8"), IPCategoryLoopback},
+	{netip.MustParsePrefix("169.254.0.0/16"), IPCategoryLinkLocal},
+	{netip.MustParsePrefix("172.16.0.0/12"), IPCategoryPrivate},
+	{netip.MustParsePrefix("192.0.0.9/32"), IPCategoryNone},  // Port Control Protocol anycast
+	{netip.MustParsePrefix("192.0.0.10/32"), IPCategoryNone}, // TURN anycast
+	{netip.MustParsePrefix("192.0.0.0/24"), IPCategoryIETFProtocol},
+	{netip.MustParsePrefix("192.0.2.0/24"), IPCategoryDocumentation},
+	{netip.MustParsePrefix("192.88.99.0/24"), IPCategoryDeprecated},
+	{netip.MustParsePrefix("192.168.0.0/16"), IPCategoryPrivate},
+	{netip.MustParsePrefix("198.18.0.0/15"), IPCategoryBenchmarking},
+	{netip.MustParsePrefix("198.51.100.0/24"), IPCategoryDocumentation},
+	{netip.MustParsePrefix("203.0.113.0/24"), IPCategoryDocumentation},
+	{netip.MustParsePrefix("224.0.0.0/4"), IPCategoryMulticast},
+	{netip.MustParsePrefix("255.255.255.255/32"), IPCategoryBroadcast},
+	{netip.MustParsePrefix("240.0.0.0/4"), IPCategoryReserved},
+
+	// IPv6 Special-Purpose Address Registry
+	{netip.MustParsePrefix("::/128"), IPCategoryUnspecified},
+	{netip.MustParsePrefix("::1/128"), IPCategoryLoopback},
+	{netip.MustParsePrefix("64:ff9b::/96"), IPCategoryNAT64},
+	{netip.MustParsePrefix("64:ff9b:1::/48"), IPCategoryNAT64},
+	{netip.MustParsePrefix("::/8"), IPCategoryReserved}, // Includes deprecated IPv4-compatible addresses
+	{netip.MustParsePrefix("100::/64"), IPCategoryDiscardOnly},
+	{netip.MustParsePrefix("2001::/32"), IPCategoryTeredo},
+	{netip.MustParsePrefix("2001:1::1/128"), IPCategoryNone}, // Port Control Protocol anycast
+	{netip.MustParsePrefix("2001:1::2/128"), IPCategoryNone}, // TURN anycast
+	{netip.MustParsePrefix("2001:1::3/128"), IPCategoryNone}, // DNS-SD Service Registration Protocol anycast
+	{netip.MustParsePrefix("2001:2::/48"), IPCategoryBenchmarking},
+	{netip.MustParsePrefix("2001:3::/32"), IPCategoryNone},     // AMT
+	{netip.MustParsePrefix("2001:4:112::/48"), IPCategoryNone}, // AS112-v6
+	{netip.MustParsePrefix("2001:20::/28"), IPCategoryNone},    // ORCHIDv2
+	{netip.MustParsePrefix("2001:30::/28"), IPCategoryNone},    // Drone Remote ID Protocol Entity Tags
+	{netip.MustParsePrefix("2001::/23"), IPCategoryIETFProtocol},
+	{netip.MustParsePrefix("2001:db8::/32"), IPCategoryDocumentation},
+	{netip.MustParsePrefix("2002::/16"), IPCategory6to4},
+	{netip.MustParsePrefix("3fff::/20"), IPCategoryDocumentation},
+	{netip.MustParsePrefix("5f00::/16"), IPCategorySegmentRouting},
+	{netip.MustParsePrefix("fc00::/7"), IPCategoryUniqueLocal},
+	{netip.MustParsePrefix("fe80::/10"), IPCategoryLinkLocal},
+	{netip.MustParsePrefix("fec0::/10"), IPCategoryDeprecated},
+	{netip.MustParsePrefix("ff00::/8"), IPCategoryMulticast},
+}
+
+// ClassifyIP returns the category of the special-purpose range the IP is in,
+// or IPCategoryNone if it is not in one. IPv4-mapped IPv6 addresses such as
+// ::ffff:127.0.0.1 are classified as the IPv4 address they map to.
+func ClassifyIP(ip net.IP) IPCategory {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return IPCategoryNone
+	}
+	return classifyAddr(addr.Unmap())
+}
+
+// classifyAddr returns the category of the special-purpose range the address
+// is in. The address must already be unmapped.
+func classifyAddr(addr netip.Addr) IPCategory {
+	for _, r := range specialPurposeRanges {
+		if r.prefix.Contains(addr) {
+			return r.category
+		}
+	}
+	return IPCategoryNone
+}
@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		category IPCategory
+	}{
+		{ip: "0.0.0.0", category: IPCategoryUnspecified},
+		{ip: "0.1.2.3", category: IPCategoryThisNetwork},
+		{ip: "10.0.0.5", category: IPCategoryPrivate},
+		{ip: "100.64.0.1", category: IPCategoryCGNAT},
+		{ip: "100.127.255.255", category: IPCategoryCGNAT},
+		{ip: "100.128.0.1", category: IPCategoryNone},
+		{ip: "100.100.100.200", category: IPCategoryMetadata},
+		{ip: "127.0.0.1", category: IPCategoryLoopback},
+		{ip: "169.254.169.254", category: IPCategoryMetadata},
+		{ip: "169.254.170.2", category: IPCategoryMetadata},
+		{ip: "169.254.1.1", category: IPCategoryLinkLocal},
+		{ip: "168.63.129.16", category: IPCategoryMetadata},
+		{ip: "172.16.0.1", category: IPCategoryPrivate},
+		{ip: "172.32.0.1", category: IPCategoryNone},
+		{ip: "192.0.0.8", category: IPCategoryIETFProtocol},
+		{ip: "192.0.0.9", category: IPCategoryNone},
+		{ip: "192.0.2.1", category: IPCategoryDocumentation},
+		{ip: "192.88.99.1", category: IPCategoryDeprecated},
+		{ip: "192.168.1.1", category: IPCategoryPrivate},
+		{ip: "198.18.0.1", category: IPCategoryBenchmarking},
+		{ip: "198.19.255.255", category: IPCategoryBenchmarking},
+		{ip: "198.51.100.1", category: IPCategoryDocumentation},
+		{ip: "203.0.113.1", category: IPCategoryDocumentation},
+		{ip: "224.0.0.1", category: IPCategoryMulticast},
+		{ip: "239.255.255.250", category: IPCategoryMulticast},
+		{ip: "240.0.0.1", category: IPCategoryReserved},
+		{ip: "255.255.255.255", category: IPCategoryBroadcast},
+		{ip: "93.184.216.34", category: IPCategoryNone},
+		{ip: "8.8.8.8", category: IPCategoryNone},
+
+		{ip: "::", category: IPCategoryUnspecified},
+		{ip: "::1", category: IPCategoryLoopback},
+		{ip: "::ffff:127.0.0.1", category: IPCategoryLoopback},
+		{ip: "::ffff:169.254.169.254", category: IPCategoryMetadata},
+		{ip: "::ffff:93.184.216.34", category: IPCategoryNone},
+		{ip: "::127.0.0.1", category: IPCategoryReserved},
+		{ip: "64:ff9b::7f00:1", category: IPCategoryNAT64},
+		{ip: "64:ff9b:1::1", category: IPCategoryNAT64},
+		{ip: "100::1", category: IPCategoryDiscardOnly},
+		{ip: "2001::1", category: IPCategoryTeredo},
+		{ip: "2001:1::1", category: IPCategoryNone},
+		{ip: "2001:1::4", category: IPCategoryIETFProtocol},
+		{ip: "2001:2::1", category: IPCategoryBenchmarking},
+		{ip: "2001:3::1", category: IPCategoryNone},
+		{ip: "2001:20::1", category: IPCategoryNone},
+		{ip: "2001:db8::1", category: IPCategoryDocumentation},
+		{ip: "2002:7f00:1::1", category: IPCategory6to4},
+		{ip: "3fff::1", category: IPCategoryDocumentation},
+		{ip: "5f00::1", category: IPCategorySegmentRouting},
+		{ip: "fc00::1", category: IPCategoryUniqueLocal},
+		{ip: "fd00:ec2::254", category: IPCategoryMetadata},
+		{ip: "fe80::1", category: IPCategoryLinkLocal},
+		{ip: "fec0::1", category: IPCategoryDeprecated},
+		{ip: "ff02::1", category: IPCategoryMulticast},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", category: IPCategoryNone},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.category, ClassifyIP(net.ParseIP(test.ip)), test.ip)
+	}
+}
+
+func TestClassifyIP_Invalid(t *testing.T) {
+	assert.Equal(t, IPCategoryNone, ClassifyIP(nil))
+}
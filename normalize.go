@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// NormalizeOptions enables the normalizations applied by Normalize which may
+// change the resource a URL refers to. They are all disabled by default.
+type NormalizeOptions struct {
+	SortQuery    bool // Whether to sort the query parameters by key, keeping the order of parameters with the same key
+	DropFragment bool // Whether to remove the fragment
+	StripWWW     bool // Whether to remove a "www." prefix from the host
+}
+
+// defaultPorts are the ports removed from URLs with these schemes
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+	"ws":    "80",
+	"wss":   "443",
+	"ftp":   "21",
+}
+
+// Normalize normalizes a URL according to RFC 3986 section 6, so equivalent
+// URLs are equal. The normalizations which preserve the resource the URL
+// refers to are always applied:
+//
+//   - The scheme and host are lowercased
+//   - The default port of the scheme and an empty port are removed
+//   - Percent-encoded unreserved characters are decoded and the hex digits of
+//     other percent-encodings are uppercased
+//   - Dot segments ("." and "..") are removed from the path
+//   - An empty path is replaced with "/" for HTTP and HTTPS URLs
+//
+// The others are enabled with opts. For example,
+// "HTTP://Example.com:80/a/./b/../c?%7e" is normalized to
+// "http://example.com/a/c?~".
+func Normalize(rawURL string, opts NormalizeOptions) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "" {
+		b.WriteString(scheme)
+		b.WriteByte(':')
+	}
+
+	if u.Opaque != "" {
+		b.WriteString(normalizeEscapes(u.Opaque))
+	} else {
+		hasAuthority := u.Host != "" || u.User != nil
+		if hasAuthority {
+			b.WriteString("//")
+			if u.User != nil {
+				b.WriteString(normalizeEscapes(u.User.String()))
+				b.WriteByte('@')
+			}
+			b.WriteString(normalizeHost(u, scheme, opts))
+		}
+
+		path := removeDotSegments(normalizeEscapes(u.EscapedPath()))
+		if path == "" && hasAuthority && (scheme == "http" || scheme == "https") {
+			path = "/"
+		}
+		b.WriteString(path)
+	}
+
+	if u.ForceQuery || u.RawQuery != "" {
+		query := normalizeEscapes(u.RawQuery)
+		if opts.SortQuery {
+			query = sortQuery(query)
+		}
+		b.WriteByte('?')
+		b.WriteString(query)
+	}
+
+	if !opts.DropFragment && (u.Fragment != "" || strings.HasSuffix(rawURL, "#")) {
+		b.WriteByte('#')
+		b.WriteString(normalizeEscapes(u.EscapedFragment()))
+	}
+
+	return b.String(), nil
+}
+
+// normalizeHost returns the lowercased host of the URL, without the default
+// port of the scheme. The zone of an IPv6 host is case-sensitive, so is kept as
+// it is, with "%" escaped again as Hostname unescapes it.
+func normalizeHost(u *url.URL, scheme string, opts NormalizeOptions) string {
+	host, zone := u.Hostname(), ""
+	if strings.Contains(host, ":") {
+		host, zone, _ = strings.Cut(host, "%")
+	}
+	host = strings.ToLower(host)
+	if opts.StripWWW && strings.HasPrefix(host, "www.") && strings.Contains(host[len("www."):], ".") {
+		host = host[len("www."):]
+	}
+	if strings.Contains(host, ":") {
+		if zone != "" {
+			host += "%25" + strings.ReplaceAll(zone, "%", "%25")
+		}
+		host = "[" + host + "]"
+	}
+
+	if port := u.Port(); port != "" && port != defaultPorts[scheme] {
+		host += ":" + port
+	}
+	return host
+}
+
+// normalizeEscapes decodes percent-encoded unreserved characters and uppercases
+// the hex digits of other percent-encodings
+func normalizeEscapes(s string) string {
+	if !strings.Contains(s, "%") {
+		return s
+	}
+
+	const upperHex = "0123456789ABCDEF"
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) && isHex(s[i+1]) && isHex(s[i+2]) {
+			c := unhex(s[i+1])<<4 | unhex(s[i+2])
+			if isUnreserved(c) {
+				b.WriteByte(c)
+			} else {
+				b.WriteByte('%')
+				b.WriteByte(upperHex[c>>4])
+				b.WriteByte(upperHex[c&15])
+			}
+			i += 2
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// removeDotSegments removes "." and ".." segments from a path, as specified in
+// RFC 3986 section 5.2.4
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+
+	out := []string{}
+	in := path
+	for in != "" {
+		switch {
+		case strings.HasPrefix(in, "../"):
+			in = in[len("../"):]
+		case strings.HasPrefix(in, "./"):
+			in = in[len("./"):]
+		case strings.HasPrefix(in, "/./"):
+			in = in[len("/."):]
+		case in == "/.":
+			in = "/"
+		case strings.HasPrefix(in, "/../"):
+			in = in[len("/.."):]
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "/..":
+			in = "/"
+			if len(out) > 0 {
+				out = out[:len(out)-1]
+			}
+		case in == "." || in == "..":
+			in = ""
+		default:
+			// Move the first segment, including its leading "/", to the output
+			end := strings.IndexByte(in[1:], '/') + 1
+			if end == 0 {
+				end = len(in)
+			}
+			out = append(out, in[:end])
+			in = in[end:]
+		}
+	}
+	return strings.Join(out, "")
+}
+
+// sortQuery sorts the parameters of a query by key, keeping the order of
+// parameters with the same key
+func sortQuery(query string) string {
+	params := strings.Split(query, "&")
+	sort.SliceStable(params, func(i, j int) bool {
+		return queryKey(params[i]) < queryKey(params[j])
+	})
+	return strings.Join(params, "&")
+}
+
+// queryKey returns the key of a query parameter
+func queryKey(param string) string {
+	if i := strings.IndexByte(param, '='); i >= 0 {
+		return param[:i]
+	}
+	return param
+}
+
+// isUnreserved reports whether c is an unreserved character, as specified in
+// RFC 3986 section 2.3
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,279 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"net/http"
+	"net/netip"
+	"time"
+)
+
+// Option configures a Verifier. Pass options to NewVerifier().
+type Option func(*Verifier)
+
+// WithHTTPCheck enables checking if the URL is reachable via HTTP
+func WithHTTPCheck() Option {
+	return func(v *Verifier) {
+		v.httpCheckEnabled = true
+	}
+}
+
+// WithHTTPCheckInternal allows checking URLs that resolve to internal IPs
+func WithHTTPCheckInternal() Option {
+	return func(v *Verifier) {
+		v.allowHttpCheckInternal = true
+	}
+}
+
+// WithAllowedCIDRs allows HTTP checks to hosts that resolve to IPs in the
+// given ranges, even if they are internal. Denied ranges take precedence.
+func WithAllowedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(v *Verifier) {
+		for _, prefix := range prefixes {
+			v.allowedCIDRs = append(v.allowedCIDRs, prefix.Masked())
+		}
+	}
+}
+
+// WithDeniedCIDRs denies HTTP checks to hosts that resolve to IPs in the given
+// ranges. This applies even if internal checks are allowed, and takes
+// precedence over allowed ranges.
+func WithDeniedCIDRs(prefixes ...netip.Prefix) Option {
+	return func(v *Verifier) {
+		for _, prefix := range prefixes {
+			v.deniedCIDRs = append(v.deniedCIDRs, prefix.Masked())
+		}
+	}
+}
+
+// WithSkipCertVerification skips certificate verification when checking HTTPS
+func WithSkipCertVerification() Option {
+	return func(v *Verifier) {
+		v.skipCertVerification = true
+	}
+}
+
+// WithTimeout sets the maximum duration of the reachability check. A timeout
+// of 0 disables the timeout.
+func WithTimeout(timeout time.Duration) Option {
+	return func(v *Verifier) {
+		v.timeout = timeout
+	}
+}
+
+// WithHTTPClient sets the HTTP client the HTTP check is based on. The client
+// is copied for each check and the internal IP and redirect policies are
+// layered on top of its transport and CheckRedirect function (see
+// WithTransport).
+func WithHTTPClient(client *http.Client) Option {
+	return func(v *Verifier) {
+		v.httpClient = client
+	}
+}
+
+// WithTransport sets the transport used by the HTTP check, e.g. to use a
+// proxy, client certificates or a tracing transport. It overrides the
+// transport of the client set with WithHTTPClient. An *http.Transport is cloned
+// and the internal IP policy enforced when dialing, while any other
+// http.RoundTripper is wrapped and the policy enforced before each request.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(v *Verifier) {
+		v.transport = transport
+	}
+}
+
+// WithMaxIdleConns sets the maximum number of idle connections kept by the
+// HTTP check transport. It has no effect on transports supplied with
+// WithTransport() or WithHTTPClient().
+func WithMaxIdleConns(n int) Option {
+	return func(v *Verifier) {
+		v.maxIdleConns = n
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the maximum number of idle connections per host
+// kept by the HTTP check transport. It has no effect on transports supplied
+// with WithTransport() or WithHTTPClient().
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(v *Verifier) {
+		v.maxIdleConnsPerHost = n
+	}
+}
+
+// WithIdleConnTimeout sets how long idle connections are kept by the HTTP
+// check transport. It has no effect on transports supplied with
+// WithTransport() or WithHTTPClient().
+func WithIdleConnTimeout(timeout time.Duration) Option {
+	return func(v *Verifier) {
+		v.idleConnTimeout = timeout
+	}
+}
+
+// WithMaxRedirects sets the maximum number of redirects followed by the HTTP
+// check. Checks which would follow more redirects fail with
+// ErrTooManyRedirects.
+func WithMaxRedirects(n int) Option {
+	return func(v *Verifier) {
+		v.maxRedirects = n
+	}
+}
+
+// WithMethodStrategy sets the HTTP method strategy of the HTTP check. HEAD
+// requests avoid downloading response bodies, with MethodHEADThenGET falling
+// back to GET when the server responds with 405 Method Not Allowed or 501 Not
+// Implemented.
+func WithMethodStrategy(strategy MethodStrategy) Option {
+	return func(v *Verifier) {
+		v.methodStrategy = strategy
+	}
+}
+
+// WithGETOnlyHosts sets hosts known not to support HEAD requests, which are
+// always checked with GET when using MethodHEADThenGET. Subdomains of the hosts
+// are included.
+func WithGETOnlyHosts(hosts ...string) Option {
+	return func(v *Verifier) {
+		v.getOnlyHosts = append(v.getOnlyHosts, hosts...)
+	}
+}
+
+// WithMaxBodyBytes sets the maximum number of bytes of a response body read by
+// the HTTP check. The body is read so the connection can be reused; bodies
+// larger than the limit are not read further and their connection is closed.
+func WithMaxBodyBytes(n int64) Option {
+	return func(v *Verifier) {
+		v.maxBodyBytes = n
+	}
+}
+
+// WithConcurrency sets the maximum number of URLs verified at once by
+// VerifyAll and VerifyStream. A limit of 0 verifies all URLs at once.
+func WithConcurrency(n int) Option {
+	return func(v *Verifier) {
+		v.concurrency = n
+	}
+}
+
+// WithHostConcurrency sets the maximum number of URLs with the same host
+// verified at once by VerifyAll and VerifyStream. A limit of 0 removes the
+// per-host limit.
+func WithHostConcurrency(n int) Option {
+	return func(v *Verifier) {
+		v.hostConcurrency = n
+	}
+}
+
+// WithRateLimit limits HTTP check requests, including redirects, to rps
+// requests per second per host with a token bucket, allowing bursts of up to
+// burst requests. Requests wait for the limiter, within the timeout of the
+// check. Hosts which respond with 429 Too Many Requests or 503 Service
+// Unavailable and a Retry-After header are not sent further requests until
+// the time it gives. The limiter is shared by all checks of the Verifier.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(v *Verifier) {
+		v.rateLimit = rps
+		v.rateLimitBurst = burst
+	}
+}
+
+// WithRateLimitByIP applies the rate limit set with WithRateLimit per IP
+// instead of per host, so hosts served from the same IP share a limit. The
+// host is resolved for each request to find its IP.
+func WithRateLimitByIP() Option {
+	return func(v *Verifier) {
+		v.rateLimitByIP = true
+	}
+}
+
+// WithRetryPolicy retries HTTP checks which fail transiently according to the
+// policy, e.g. DefaultRetryPolicy(). Retries stop when the context is done or
+// its deadline would pass during the backoff. The outcome of each attempt is
+// recorded in HTTP.Attempts.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(v *Verifier) {
+		v.retryPolicy = &policy
+	}
+}
+
+// WithCache caches the results of Verify in the cache, e.g. NewLRUCache(n),
+// and collapses concurrent verifications of the same URL into one. Results are
+// keyed by the URL, normalized with Normalize, and the configuration of the
+// verifier, so a cache can be shared by verifiers. Verifiers only share results
+// if they use the same resolver, client, transport and TLD list values, as
+// these are compared by identity.
+func WithCache(cache Cache) Option {
+	return func(v *Verifier) {
+		v.cache = cache
+	}
+}
+
+// WithCacheTTL sets how long successful results and unsuccessful results
+// (errors, invalid URLs and unsuccessful HTTP checks) are cached. A TTL of 0
+// disables caching of those results.
+func WithCacheTTL(ttl, negativeTTL time.Duration) Option {
+	return func(v *Verifier) {
+		v.cacheTTL = ttl
+		v.negativeCacheTTL = negativeTTL
+	}
+}
+
+// WithNormalizeOptions enables normalizations of Result.NormalizedURL which may
+// change the resource the URL refers to, such as sorting the query parameters
+func WithNormalizeOptions(opts NormalizeOptions) Option {
+	return func(v *Verifier) {
+		v.normalizeOptions = opts
+	}
+}
+
+// WithStripTracking strips tracking parameters from URLs into Result.CleanURL,
+// recording them in Result.RemovedParams. If no rules are given,
+// DefaultTrackingRules() are used. To add rules to the built-in ones, pass
+// append(DefaultTrackingRules(), rules...).
+func WithStripTracking(rules ...TrackingRule) Option {
+	return func(v *Verifier) {
+		v.stripTracking = true
+		v.trackingRules = rules
+		if len(rules) == 0 {
+			v.trackingRules = DefaultTrackingRules()
+		}
+	}
+}
+
+// WithPublicSuffixList sets the Public Suffix List of Result.Domain, e.g. a
+// newer list than the embedded one, parsed with ParsePublicSuffixList()
+func WithPublicSuffixList(list *PublicSuffixList) Option {
+	return func(v *Verifier) {
+		v.publicSuffixList = list
+	}
+}
+
+// WithTLDList sets the TLD list of Result.HasKnownTLD, e.g. a newer list than
+// the embedded one, parsed with ParseTLDList()
+func WithTLDList(list *TLDList) Option {
+	return func(v *Verifier) {
+		v.tldList = list
+	}
+}
+
+// WithRequireKnownTLD treats URLs whose host is a domain name without a known
+// TLD, e.g. "example.cmo", as invalid: IsURL is false and, if the HTTP check is
+// enabled, Verify returns ErrUnknownTLD without checking the URL. IPs are not
+// affected.
+func WithRequireKnownTLD() Option {
+	return func(v *Verifier) {
+		v.requireKnownTLD = true
+	}
+}
+
+// WithResolver sets the resolver used to look up hosts
+func WithResolver(resolver Resolver) Option {
+	return func(v *Verifier) {
+		v.resolver = resolver
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent by the HTTP check
+func WithUserAgent(userAgent string) Option {
+	return func(v *Verifier) {
+		v.userAgent = userAgent
+	}
+}
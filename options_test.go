@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewVerifier_Defaults(t *testing.T) {
+	verifier := NewVerifier()
+
+	assert.False(t, verifier.httpCheckEnabled)
+	assert.False(t, verifier.allowHttpCheckInternal)
+	assert.False(t, verifier.skipCertVerification)
+	assert.Equal(t, net.DefaultResolver, verifier.resolver)
+	assert.Equal(t, DefaultTimeout, verifier.timeout)
+	assert.Nil(t, verifier.httpClient)
+	assert.Equal(t, "", verifier.userAgent)
+}
+
+func TestNewVerifier_Options(t *testing.T) {
+	client := &http.Client{}
+	resolver := &stubResolver{}
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithSkipCertVerification(),
+		WithTimeout(5*time.Second),
+		WithHTTPClient(client),
+		WithResolver(resolver),
+		WithUserAgent("url-verifier-test"),
+	)
+
+	assert.True(t, verifier.httpCheckEnabled)
+	assert.True(t, verifier.allowHttpCheckInternal)
+	assert.True(t, verifier.skipCertVerification)
+	assert.Equal(t, 5*time.Second, verifier.timeout)
+	assert.Equal(t, client, verifier.httpClient)
+	assert.Equal(t, resolver, verifier.resolver)
+	assert.Equal(t, "url-verifier-test", verifier.userAgent)
+}
+
+func TestNewVerifier_OptionsCompatibleWithToggles(t *testing.T) {
+	verifier := NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal())
+	verifier.DisableHTTPCheck()
+	verifier.DisallowHTTPCheckInternal()
+
+	assert.False(t, verifier.httpCheckEnabled)
+	assert.False(t, verifier.allowHttpCheckInternal)
+}
+
+func TestWithUserAgent(t *testing.T) {
+	userAgent := ""
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent = r.UserAgent()
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithUserAgent("url-verifier-test"))
+	_, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "url-verifier-test", userAgent)
+}
+
+func TestWithResolver(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urlToCheck := fmt.Sprintf("http://resolver.example:%s/", tsURL.Port())
+
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+	)
+	ret, err := verifier.Verify(urlToCheck)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: urlToCheck}, withoutTiming(ret.HTTP))
+}
+
+func TestWithHTTPClient_CheckRedirect(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	// The client's own redirect policy is applied after the verifier's
+	client := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithHTTPClient(client))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 302, IsSuccess: true, Method: "GET", FinalURL: ts.URL}, withoutTiming(ret))
+}
+
+func TestWithHTTPClient_InternalIPPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	client := &http.Client{Transport: &http.Transport{}}
+
+	verifier := NewVerifier(WithHTTPClient(client))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.ErrorContains(t, err, "resolves to an internal IP 127.0.0.1")
+}
@@ -0,0 +1,155 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+//go:generate curl -fsSL -o data/public_suffix_list.dat https://publicsuffix.org/list/public_suffix_list.dat
+
+// publicSuffixListData is the embedded Public Suffix List
+//
+//go:embed data/public_suffix_list.dat
+var publicSuffixListData []byte
+
+var (
+	defaultPublicSuffixListOnce sync.Once
+	defaultPublicSuffixList     *PublicSuffixList
+)
+
+// PublicSuffixList is a parsed Public Suffix List (https://publicsuffix.org),
+// which lists the suffixes under which anyone can register a domain. Create
+// one using ParsePublicSuffixList() or use DefaultPublicSuffixList(). It is
+// safe for concurrent use.
+type PublicSuffixList struct {
+	rules      map[string]bool // Normal rules, e.g. "co.uk", and whether they are private
+	wildcards  map[string]bool // Wildcard rules without the "*.", e.g. "ck" for "*.ck", and whether they are private
+	exceptions map[string]bool // Exception rules without the "!", e.g. "www.ck" for "!www.ck", and whether they are private
+}
+
+// Domain is the breakdown of a host by the Public Suffix List
+type Domain struct {
+	PublicSuffix      string   `json:"public_suffix"`      // The public suffix (eTLD), e.g. "co.uk" or "github.io"
+	RegistrableDomain string   `json:"registrable_domain"` // The public suffix plus one label (eTLD+1), e.g. "example.co.uk", empty if the host is a public suffix
+	Subdomains        []string `json:"subdomains"`         // The labels before the registrable domain, e.g. ["a", "b"] for "a.b.example.co.uk"
+	ICANN             bool     `json:"icann"`              // Whether the public suffix is in the ICANN section of the list
+	Private           bool     `json:"private"`            // Whether the public suffix is in the private section of the list, e.g. "github.io"
+}
+
+// DefaultPublicSuffixList returns the Public Suffix List embedded in the
+// package, parsing it on first use
+func DefaultPublicSuffixList() *PublicSuffixList {
+	defaultPublicSuffixListOnce.Do(func() {
+		list, err := ParsePublicSuffixList(bytes.NewReader(publicSuffixListData))
+		if err != nil {
+			panic(fmt.Sprintf("urlverifier: parsing the embedded public suffix list: %s", err))
+		}
+		defaultPublicSuffixList = list
+	})
+	return defaultPublicSuffixList
+}
+
+// ParsePublicSuffixList parses a Public Suffix List in the format of
+// https://publicsuffix.org/list/public_suffix_list.dat, e.g. a newer version
+// than the embedded one
+func ParsePublicSuffixList(r io.Reader) (*PublicSuffixList, error) {
+	l := &PublicSuffixList{rules: map[string]bool{}, wildcards: map[string]bool{}, exceptions: map[string]bool{}}
+
+	private := false
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case strings.HasPrefix(line, "// ===BEGIN PRIVATE DOMAINS==="):
+			private = true
+			continue
+		case strings.HasPrefix(line, "// ===END PRIVATE DOMAINS==="):
+			private = false
+			continue
+		case line == "" || strings.HasPrefix(line, "//"):
+			continue
+		}
+
+		// Rules end at the first whitespace
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i]
+		}
+
+		switch {
+		case strings.HasPrefix(line, "!"):
+			l.exceptions[toASCII(line[1:])] = private
+		case strings.HasPrefix(line, "*."):
+			l.wildcards[toASCII(line[2:])] = private
+		default:
+			l.rules[toASCII(line)] = private
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.rules) == 0 && len(l.wildcards) == 0 {
+		return nil, fmt.Errorf("the public suffix list has no rules")
+	}
+	return l, nil
+}
+
+// Lookup returns the breakdown of the host by the list, or nil if the host is
+// an IP or is not a valid domain name. Hosts which do not match any rule have
+// their last label as the public suffix, with neither ICANN nor Private set.
+func (l *PublicSuffixList) Lookup(host string) *Domain {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil {
+		return nil
+	}
+
+	labels := strings.Split(host, ".")
+	asciiLabels := strings.Split(toASCII(host), ".")
+	for _, label := range labels {
+		if label == "" {
+			return nil
+		}
+	}
+
+	// The number of labels of the public suffix, and its section
+	suffixLen, icann, private := 1, false, false
+	for i := range asciiLabels {
+		suffix := strings.Join(asciiLabels[i:], ".")
+
+		if p, ok := l.exceptions[suffix]; ok {
+			suffixLen, icann, private = len(asciiLabels)-i-1, !p, p
+			break
+		}
+		if p, ok := l.rules[suffix]; ok {
+			suffixLen, icann, private = len(asciiLabels)-i, !p, p
+			break
+		}
+		if i+1 < len(asciiLabels) {
+			if p, ok := l.wildcards[strings.Join(asciiLabels[i+1:], ".")]; ok {
+				suffixLen, icann, private = len(asciiLabels)-i, !p, p
+				break
+			}
+		}
+	}
+
+	d := &Domain{
+		PublicSuffix: strings.Join(labels[len(labels)-suffixLen:], "."),
+		ICANN:        icann,
+		Private:      private,
+	}
+	if len(labels) > suffixLen {
+		registrable := len(labels) - suffixLen - 1
+		d.RegistrableDomain = strings.Join(labels[registrable:], ".")
+		if registrable > 0 {
+			d.Subdomains = labels[:registrable]
+		}
+	}
+	return d
+}
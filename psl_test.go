@@ -0,0 +1,87 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicSuffixList_Lookup(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected *Domain
+	}{
+		{"example.com", &Domain{PublicSuffix: "com", RegistrableDomain: "example.com", ICANN: true}},
+		{"a.b.example.co.uk", &Domain{PublicSuffix: "co.uk", RegistrableDomain: "example.co.uk", Subdomains: []string{"a", "b"}, ICANN: true}},
+		{"WWW.Example.COM.", &Domain{PublicSuffix: "com", RegistrableDomain: "example.com", Subdomains: []string{"www"}, ICANN: true}},
+		{"co.uk", &Domain{PublicSuffix: "co.uk", ICANN: true}},
+		{"user.github.io", &Domain{PublicSuffix: "github.io", RegistrableDomain: "user.github.io", Private: true}},
+		{"docs.user.github.io", &Domain{PublicSuffix: "github.io", RegistrableDomain: "user.github.io", Subdomains: []string{"docs"}, Private: true}},
+		{"github.io", &Domain{PublicSuffix: "github.io", Private: true}},
+		{"a.b.ck", &Domain{PublicSuffix: "b.ck", RegistrableDomain: "a.b.ck", ICANN: true}},
+		{"www.ck", &Domain{PublicSuffix: "ck", RegistrableDomain: "www.ck", ICANN: true}},
+		{"a.www.ck", &Domain{PublicSuffix: "ck", RegistrableDomain: "www.ck", Subdomains: []string{"a"}, ICANN: true}},
+		{"example.中国", &Domain{PublicSuffix: "中国", RegistrableDomain: "example.中国", ICANN: true}},
+		{"example.xn--fiqs8s", &Domain{PublicSuffix: "xn--fiqs8s", RegistrableDomain: "example.xn--fiqs8s", ICANN: true}},
+		{"example.notatld", &Domain{PublicSuffix: "notatld", RegistrableDomain: "example.notatld"}},
+		{"localhost", &Domain{PublicSuffix: "localhost"}},
+		{"127.0.0.1", nil},
+		{"::1", nil},
+		{"a..example.com", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultPublicSuffixList().Lookup(tt.host))
+		})
+	}
+}
+
+func TestParsePublicSuffixList(t *testing.T) {
+	list, err := ParsePublicSuffixList(strings.NewReader(`// ===BEGIN ICANN DOMAINS===
+example
+*.wild.example
+!special.wild.example
+// ===END ICANN DOMAINS===
+// ===BEGIN PRIVATE DOMAINS===
+hosting.example  trailing text is ignored
+// ===END PRIVATE DOMAINS===
+`))
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, &Domain{PublicSuffix: "example", RegistrableDomain: "site.example", ICANN: true}, list.Lookup("site.example"))
+		assert.Equal(t, &Domain{PublicSuffix: "a.wild.example", RegistrableDomain: "site.a.wild.example", ICANN: true}, list.Lookup("site.a.wild.example"))
+		assert.Equal(t, &Domain{PublicSuffix: "wild.example", RegistrableDomain: "special.wild.example", ICANN: true}, list.Lookup("special.wild.example"))
+		assert.Equal(t, &Domain{PublicSuffix: "hosting.example", RegistrableDomain: "site.hosting.example", Private: true}, list.Lookup("site.hosting.example"))
+		assert.Equal(t, &Domain{PublicSuffix: "com", RegistrableDomain: "example.com"}, list.Lookup("example.com"))
+	}
+
+	_, err = ParsePublicSuffixList(strings.NewReader("// No rules\n"))
+	assert.EqualError(t, err, "the public suffix list has no rules")
+}
+
+func TestVerify_Domain(t *testing.T) {
+	ret, err := NewVerifier().Verify("https://a.b.example.co.uk/")
+	assert.Nil(t, err)
+	assert.Equal(t, &Domain{PublicSuffix: "co.uk", RegistrableDomain: "example.co.uk", Subdomains: []string{"a", "b"}, ICANN: true}, ret.Domain)
+
+	list, err := ParsePublicSuffixList(strings.NewReader("example.co.uk\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ret, err = NewVerifier(WithPublicSuffixList(list)).Verify("https://a.b.example.co.uk/")
+	assert.Nil(t, err)
+	assert.Equal(t, &Domain{PublicSuffix: "example.co.uk", RegistrableDomain: "b.example.co.uk", Subdomains: []string{"a"}, ICANN: true}, ret.Domain)
+}
+
+func TestToASCII(t *testing.T) {
+	assert.Equal(t, "example.com", toASCII("Example.COM"))
+	assert.Equal(t, "xn--bcher-kva.example", toASCII("Bücher.example"))
+	assert.Equal(t, "example.xn--fiqs8s", toASCII("example.中国"))
+	assert.Equal(t, "xn--e1afmkfd.xn--80akhbyknj4f", toASCII("пример.испытание"))
+	assert.Equal(t, "xn--eckwd4c7cu47r2wf", toASCII("ドメイン名例"))
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Punycode parameters, as specified in RFC 3492 section 5
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+)
+
+// toASCII returns the lowercased host with its non-ASCII labels encoded with
+// Punycode, e.g. "example.xn--fiqs8s" for "example.中国"
+func toASCII(host string) string {
+	host = strings.ToLower(host)
+	if isASCII(host) {
+		return host
+	}
+
+	labels := strings.Split(host, ".")
+	for i, label := range labels {
+		if !isASCII(label) {
+			labels[i] = "xn--" + encodePunycode(label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// encodePunycode encodes a label with Punycode, as specified in RFC 3492
+// section 6.3, without the "xn--" prefix
+func encodePunycode(label string) string {
+	runes := []rune(label)
+
+	out := []byte{}
+	for _, r := range runes {
+		if r < utf8.RuneSelf {
+			out = append(out, byte(r))
+		}
+	}
+	basic := len(out)
+	if basic > 0 {
+		out = append(out, '-')
+	}
+
+	n, delta, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for handled := basic; handled < len(runes); {
+		// Find the smallest code point not yet handled
+		m := rune(utf8.MaxRune)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := k - bias
+				if t < punycodeTMin {
+					t = punycodeTMin
+				} else if t > punycodeTMax {
+					t = punycodeTMax
+				}
+				if q < t {
+					break
+				}
+				out = append(out, punycodeDigit(t+(q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out = append(out, punycodeDigit(q))
+
+			bias = adaptPunycodeBias(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return string(out)
+}
+
+// adaptPunycodeBias adapts the bias after a code point is encoded, as specified
+// in RFC 3492 section 6.1
+func adaptPunycodeBias(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
+
+// punycodeDigit returns the character of a Punycode digit
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+// isASCII reports whether s only contains ASCII characters
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,92 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/davidmytton/url-verifier/internal/ratelimit"
+)
+
+// rateLimitRoundTripper waits for the rate limiter before passing each request
+// on, and blocks further requests to a host which responds with a Retry-After
+// header
+type rateLimitRoundTripper struct {
+	v    *Verifier
+	next http.RoundTripper
+}
+
+func (r *rateLimitRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, err := r.v.rateLimitKey(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.v.limiter.Wait(req.Context(), key); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		if errors.Is(err, ratelimit.ErrDeadline) {
+			return nil, fmt.Errorf("unable to check if the URL is reachable via HTTP: requests to %s are rate limited: %w", key, ErrRateLimited)
+		}
+		return nil, err
+	}
+
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+			r.v.limiter.Block(key, time.Now().Add(delay))
+		}
+	}
+	return resp, nil
+}
+
+// rateLimitKey returns the key of the rate limiter bucket of the request: its
+// host, or the first IP the host resolves to if rate limiting by IP
+func (v *Verifier) rateLimitKey(req *http.Request) (string, error) {
+	host := strings.ToLower(strings.TrimSuffix(req.URL.Hostname(), "."))
+	if !v.rateLimitByIP {
+		return host, nil
+	}
+
+	ips, err := v.lookupIP(req.Context(), host)
+	if err != nil {
+		return "", err
+	}
+	if len(ips) == 0 {
+		return host, nil
+	}
+	return ips[0].String(), nil
+}
+
+// parseRetryAfter parses the value of a Retry-After header, which is either a
+// number of seconds or a HTTP date, returning how long to wait from now
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		if delay := t.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
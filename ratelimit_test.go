@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		delay time.Duration
+		ok    bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{"Mon, 01 Jan 2024 12:00:30 GMT", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"", 0, false},
+		{"-1", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			delay, ok := parseRetryAfter(test.value, now)
+			assert.Equal(t, test.delay, delay)
+			assert.Equal(t, test.ok, ok)
+		})
+	}
+}
+
+func TestWithRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRateLimit(20, 1))
+
+	// Concurrent checks share the limiter
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := verifier.CheckHTTP(ts.URL)
+			assert.Nil(t, err)
+		}()
+	}
+	wg.Wait()
+
+	assert.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestWithRateLimit_RetryAfter(t *testing.T) {
+	for _, status := range []int{http.StatusTooManyRequests, http.StatusServiceUnavailable} {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(status)
+			}))
+			defer ts.Close()
+
+			verifier := NewVerifier(WithHTTPCheckInternal(), WithRateLimit(100, 10))
+			ret, err := verifier.CheckHTTP(ts.URL)
+
+			assert.Nil(t, err)
+			assert.Equal(t, status, ret.StatusCode)
+
+			// Further checks of the host would wait until the Retry-After
+			// time, which is beyond the timeout, so fail without waiting
+			verifier.SetTimeout(time.Second)
+			start := time.Now()
+			_, err = verifier.CheckHTTP(ts.URL)
+
+			assert.ErrorIs(t, err, ErrRateLimited)
+			assert.NotErrorIs(t, err, ErrTimeout)
+			assert.Less(t, time.Since(start), 500*time.Millisecond)
+		})
+	}
+}
+
+func TestWithRateLimit_RetryAfterIgnoredForOtherStatus(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "60")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRateLimit(100, 10), WithTimeout(time.Second))
+	for i := 0; i < 2; i++ {
+		_, err := verifier.CheckHTTP(ts.URL)
+		assert.Nil(t, err)
+	}
+}
+
+func TestWithRateLimitByIP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewVerifier(
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+		WithRateLimit(100, 1),
+		WithRateLimitByIP(),
+	)
+
+	for _, host := range []string{"a.example", "b.example"} {
+		_, err := verifier.CheckHTTP(fmt.Sprintf("http://%s:%s/", host, tsURL.Port()))
+		assert.Nil(t, err)
+	}
+
+	// Both hosts share the bucket of their IP
+	assert.Equal(t, []string{"127.0.0.1"}, verifier.limiter.Keys())
+}
@@ -0,0 +1,81 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Hop is a redirect followed by the HTTP check
+type Hop struct {
+	URL        string        `json:"url"`         // The URL which responded with the redirect
+	StatusCode int           `json:"status_code"` // The redirect status code
+	Location   string        `json:"location"`    // The Location header of the redirect
+	Latency    time.Duration `json:"latency"`     // The time between sending the request and following the redirect
+}
+
+// redirectRecorderKey is the context key of the redirectRecorder of a request
+type redirectRecorderKey struct{}
+
+// redirectRecorder records the redirects followed by a HTTP check. It is
+// passed to checkRedirect in the request context because the client is shared
+// between checks.
+type redirectRecorder struct {
+	start time.Time // When the current request was sent
+	hops  []Hop
+}
+
+// recordRedirect records the redirect response which led to req with the
+// redirectRecorder in the request context, if any
+func recordRedirect(req *http.Request, via []*http.Request) {
+	if recorder, ok := req.Context().Value(redirectRecorderKey{}).(*redirectRecorder); ok {
+		recorder.record(req, via)
+	}
+}
+
+// record records the redirect response which led to req
+func (r *redirectRecorder) record(req *http.Request, via []*http.Request) {
+	now := time.Now()
+	hop := Hop{
+		URL:     via[len(via)-1].URL.Redacted(),
+		Latency: now.Sub(r.start),
+	}
+	if req.Response != nil {
+		hop.StatusCode = req.Response.StatusCode
+		hop.Location = req.Response.Header.Get("Location")
+	}
+
+	r.hops = append(r.hops, hop)
+	r.start = now
+}
+
+// checkRedirect is the redirect policy of the HTTP check. Each redirect is
+// checked against the maximum number of redirects, for loops, for a HTTP or
+// HTTPS scheme and for hosts that resolve to IPs refused by the IP policy.
+func (v *Verifier) checkRedirect(req *http.Request, via []*http.Request) error {
+	hop := len(via)
+	if hop > v.maxRedirects {
+		return &RedirectError{Hop: hop, URL: req.URL.Redacted(), Err: fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, v.maxRedirects)}
+	}
+
+	for _, r := range via {
+		if r.URL.String() == req.URL.String() {
+			return &RedirectError{Hop: hop, URL: req.URL.Redacted(), Err: ErrRedirectLoop}
+		}
+	}
+
+	if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+		return &RedirectError{Hop: hop, URL: req.URL.Redacted(), Err: ErrUnsupportedScheme}
+	}
+
+	if !v.ipPolicyEnabled() {
+		return nil
+	}
+
+	if _, err := v.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		return &RedirectError{Hop: hop, URL: req.URL.Redacted(), Err: err}
+	}
+
+	return nil
+}
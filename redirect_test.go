@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckHTTP_RedirectChain(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/moved", http.StatusMovedPermanently)
+		case "/moved":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			fmt.Fprintln(w, "Hello, client")
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL + "/")
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.Equal(t, 200, ret.StatusCode)
+	assert.Equal(t, ts.URL+"/final", ret.FinalURL)
+
+	if assert.Len(t, ret.Redirects, 2) {
+		assert.Equal(t, ts.URL+"/", ret.Redirects[0].URL)
+		assert.Equal(t, http.StatusMovedPermanently, ret.Redirects[0].StatusCode)
+		assert.Equal(t, "/moved", ret.Redirects[0].Location)
+		assert.Greater(t, int64(ret.Redirects[0].Latency), int64(0))
+
+		assert.Equal(t, ts.URL+"/moved", ret.Redirects[1].URL)
+		assert.Equal(t, http.StatusFound, ret.Redirects[1].StatusCode)
+		assert.Equal(t, "/final", ret.Redirects[1].Location)
+	}
+}
+
+func TestCheckHTTP_RedirectChainRedacted(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP("http://user:secret@" + ts.Listener.Addr().String() + "/")
+
+	assert.Nil(t, err)
+	if assert.Len(t, ret.Redirects, 1) {
+		assert.Equal(t, "http://user:xxxxx@"+ts.Listener.Addr().String()+"/", ret.Redirects[0].URL)
+	}
+}
+
+func TestWithMaxRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			http.Redirect(w, r, "/1", http.StatusFound)
+		case "/1":
+			http.Redirect(w, r, "/2", http.StatusFound)
+		default:
+			fmt.Fprintln(w, "Hello, client")
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithMaxRedirects(2))
+	ret, err := verifier.CheckHTTP(ts.URL + "/")
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.Len(t, ret.Redirects, 2)
+
+	verifier = NewVerifier(WithHTTPCheckInternal(), WithMaxRedirects(1))
+	ret, err = verifier.CheckHTTP(ts.URL + "/")
+
+	var verifyErr *Error
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+	assert.ErrorContains(t, err, "stopped after 1 redirects")
+	assert.True(t, errors.As(err, &verifyErr))
+	assert.Equal(t, PhaseRedirect, verifyErr.Phase)
+	assert.False(t, ret.Reachable)
+	assert.Len(t, ret.Redirects, 2)
+}
+
+func TestWithMaxRedirects_Disabled(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/elsewhere", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithMaxRedirects(0))
+	_, err := verifier.CheckHTTP(ts.URL)
+
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+}
+
+func TestCheckHTTP_RedirectLoop(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		default:
+			http.Redirect(w, r, "/a", http.StatusFound)
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL + "/a")
+
+	var redirectErr *RedirectError
+	assert.ErrorIs(t, err, ErrRedirectLoop)
+	assert.True(t, errors.As(err, &redirectErr))
+	assert.Equal(t, 2, redirectErr.Hop)
+	assert.Equal(t, ts.URL+"/a", redirectErr.URL)
+	assert.False(t, ret.Reachable)
+	assert.Len(t, ret.Redirects, 2)
+}
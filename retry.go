@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// RetryPolicy configures retrying HTTP checks which fail transiently. Set it
+// with WithRetryPolicy().
+type RetryPolicy struct {
+	MaxAttempts          int           // The maximum number of attempts, including the first
+	BaseBackoff          time.Duration // The backoff before the first retry, doubled for each further retry
+	MaxBackoff           time.Duration // The maximum backoff, 0 for no maximum
+	Jitter               float64       // The fraction of each backoff which is randomized, between 0 and 1
+	RetryableStatusCodes []int         // The HTTP status codes which are retried
+	RetryableErrors      []error       // The sentinel errors, e.g. ErrConnection, of errors which are retried
+}
+
+// DefaultRetryPolicy returns a retry policy which makes up to 3 attempts,
+// retrying connection errors, timeouts and 429, 502, 503 and 504 responses
+// with a backoff of 200ms, doubled for each retry up to 5s, and 20% jitter
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: 3,
+		BaseBackoff: 200 * time.Millisecond,
+		MaxBackoff:  5 * time.Second,
+		Jitter:      0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+		RetryableErrors: []error{ErrConnection, ErrTimeout},
+	}
+}
+
+// Attempt is the outcome of an attempt of a HTTP check
+type Attempt struct {
+	StatusCode int           `json:"status_code"` // The HTTP status code, 0 if the attempt failed
+	Error      string        `json:"error"`       // The error, if the attempt failed
+	Duration   time.Duration `json:"duration"`    // The duration of the attempt
+	Backoff    time.Duration `json:"backoff"`     // The backoff before the next attempt, 0 if it was the last
+}
+
+// jitterRand randomizes backoffs
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// attemptWithRetries attempts the HTTP check, retrying according to the retry
+// policy, and records the outcome of each attempt on ret. If the context is
+// done or its deadline would pass during a backoff, the last outcome is
+// returned.
+func (v *Verifier) attemptWithRetries(ctx context.Context, ret *HTTP, urlToCheck string) (*http.Response, error) {
+	policy := v.retryPolicy
+	if policy == nil {
+		return v.attempt(ctx, ret, urlToCheck)
+	}
+
+	for n := 1; ; n++ {
+		start := time.Now()
+		resp, err := v.attempt(ctx, ret, urlToCheck)
+
+		outcome := Attempt{Duration: time.Since(start)}
+		if err != nil {
+			outcome.Error = err.Error()
+		} else {
+			outcome.StatusCode = resp.StatusCode
+		}
+
+		retry := n < policy.MaxAttempts && ctx.Err() == nil && policy.retryable(resp, err)
+		if retry {
+			outcome.Backoff = policy.backoff(n, resp)
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < outcome.Backoff {
+				retry = false
+				outcome.Backoff = 0
+			}
+		}
+		ret.Attempts = append(ret.Attempts, outcome)
+
+		if !retry {
+			return resp, err
+		}
+
+		if resp != nil {
+			drainBody(resp.Body, v.maxBodyBytes)
+		}
+
+		timer := time.NewTimer(outcome.Backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return resp, err
+		}
+	}
+}
+
+// retryable reports whether the outcome of an attempt is retried
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		for _, kind := range p.RetryableErrors {
+			if errors.Is(err, kind) {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, code := range p.RetryableStatusCodes {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the backoff after the nth attempt. It is at least as long as
+// the Retry-After header of a 429 or 503 response asks.
+func (p *RetryPolicy) backoff(n int, resp *http.Response) time.Duration {
+	backoff := p.BaseBackoff
+	for i := 1; i < n && (p.MaxBackoff <= 0 || backoff < p.MaxBackoff); i++ {
+		backoff *= 2
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		backoff = p.MaxBackoff
+	}
+
+	if p.Jitter > 0 {
+		jitterMu.Lock()
+		backoff -= time.Duration(p.Jitter * jitterRand.Float64() * float64(backoff))
+		jitterMu.Unlock()
+	}
+
+	if resp != nil && (resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()); ok && retryAfter > backoff {
+			backoff = retryAfter
+		}
+	}
+	return backoff
+}
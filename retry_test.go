@@ -0,0 +1,205 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testRetryPolicy is the default retry policy with short backoffs
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.BaseBackoff = 10 * time.Millisecond
+	policy.MaxBackoff = 50 * time.Millisecond
+	return policy
+}
+
+func TestCheckHTTP_Retry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.Equal(t, http.StatusOK, ret.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requests))
+	if assert.Len(t, ret.Attempts, 2) {
+		assert.Equal(t, http.StatusServiceUnavailable, ret.Attempts[0].StatusCode)
+		assert.GreaterOrEqual(t, ret.Attempts[0].Backoff, 8*time.Millisecond)
+		assert.Equal(t, http.StatusOK, ret.Attempts[1].StatusCode)
+		assert.Equal(t, time.Duration(0), ret.Attempts[1].Backoff)
+	}
+}
+
+func TestCheckHTTP_RetryMaxAttempts(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	// The last outcome is returned
+	assert.Nil(t, err)
+	assert.False(t, ret.IsSuccess)
+	assert.Equal(t, http.StatusBadGateway, ret.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requests))
+	assert.Len(t, ret.Attempts, 3)
+}
+
+func TestCheckHTTP_RetryNotRetryable(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		http.NotFound(w, r)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, ret.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	assert.Len(t, ret.Attempts, 1)
+}
+
+func TestCheckHTTP_RetryConnectionError(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			// Close the connection without a response
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	if assert.Len(t, ret.Attempts, 2) {
+		assert.Equal(t, 0, ret.Attempts[0].StatusCode)
+		assert.NotEmpty(t, ret.Attempts[0].Error)
+		assert.Equal(t, http.StatusOK, ret.Attempts[1].StatusCode)
+	}
+}
+
+func TestCheckHTTP_RetryDNSFailureNotRetryable(t *testing.T) {
+	verifier := NewVerifier(WithResolver(notFoundResolver{}), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP("http://example.unreachable")
+
+	assert.ErrorIs(t, err, ErrDNSFailure)
+	assert.Len(t, ret.Attempts, 1)
+}
+
+func TestCheckHTTP_RetryDeadline(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	policy := testRetryPolicy()
+	policy.BaseBackoff = time.Second
+	policy.MaxBackoff = time.Second
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(policy))
+	start := time.Now()
+	ret, err := verifier.CheckHTTPContext(ctx, ts.URL)
+
+	// The backoff would pass the deadline, so the check is not retried
+	assert.Nil(t, err)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+	assert.Equal(t, http.StatusServiceUnavailable, ret.StatusCode)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requests))
+	if assert.Len(t, ret.Attempts, 1) {
+		assert.Equal(t, time.Duration(0), ret.Attempts[0].Backoff)
+	}
+}
+
+func TestCheckHTTP_RetryAfter(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithRetryPolicy(testRetryPolicy()))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	if assert.Len(t, ret.Attempts, 2) {
+		assert.Equal(t, time.Second, ret.Attempts[0].Backoff)
+	}
+}
+
+func TestCheckHTTP_NoRetryPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Nil(t, ret.Attempts)
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+
+	assert.Equal(t, 100*time.Millisecond, policy.backoff(1, nil))
+	assert.Equal(t, 200*time.Millisecond, policy.backoff(2, nil))
+	assert.Equal(t, 400*time.Millisecond, policy.backoff(3, nil))
+	assert.Equal(t, time.Second, policy.backoff(5, nil))
+	assert.Equal(t, time.Second, policy.backoff(100, nil))
+
+	policy.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		backoff := policy.backoff(2, nil)
+		assert.GreaterOrEqual(t, backoff, 100*time.Millisecond)
+		assert.LessOrEqual(t, backoff, 200*time.Millisecond)
+	}
+}
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := DefaultRetryPolicy()
+
+	assert.True(t, policy.retryable(&http.Response{StatusCode: http.StatusServiceUnavailable}, nil))
+	assert.False(t, policy.retryable(&http.Response{StatusCode: http.StatusInternalServerError}, nil))
+	assert.True(t, policy.retryable(nil, &Error{Kind: ErrConnection, Err: &net.OpError{Op: "dial"}}))
+	assert.True(t, policy.retryable(nil, &Error{Kind: ErrTimeout}))
+	assert.False(t, policy.retryable(nil, &Error{Kind: ErrTLS}))
+	assert.False(t, policy.retryable(nil, &Error{Kind: ErrInternalIP}))
+}
@@ -0,0 +1,63 @@
+// SPDX-License-Identifier: MIT
+package server
+
+import (
+	"net/http"
+
+	urlverifier "github.com/davidmytton/url-verifier"
+)
+
+// Option configures a Server
+type Option func(*Server)
+
+// WithVerifierOptions sets the options the verifier is created with, e.g.
+// urlverifier.WithHTTPCheck() to check if URLs are reachable. The internal IP
+// check is enforced regardless, unless WithAllowInternal() is set.
+func WithVerifierOptions(opts ...urlverifier.Option) Option {
+	return func(s *Server) {
+		s.verifierOpts = append(s.verifierOpts, opts...)
+	}
+}
+
+// WithAllowInternal allows HTTP checks to hosts that resolve to internal IPs.
+// Only use this if the API is not exposed to untrusted clients.
+func WithAllowInternal() Option {
+	return func(s *Server) {
+		s.allowInternal = true
+		s.verifierOpts = append(s.verifierOpts, urlverifier.WithHTTPCheckInternal())
+	}
+}
+
+// WithMaxRequestBytes sets the maximum size of a request body. Set it to 0 for
+// no limit.
+func WithMaxRequestBytes(n int64) Option {
+	return func(s *Server) {
+		s.maxRequestBytes = n
+	}
+}
+
+// WithMaxBatchSize sets the maximum number of URLs of a batch request. Set it
+// to 0 for no limit.
+func WithMaxBatchSize(n int) Option {
+	return func(s *Server) {
+		s.maxBatchSize = n
+	}
+}
+
+// WithRateLimit limits the number of URLs each client may verify to rps per
+// second, with bursts of up to burst URLs. Set rps to 0 for no limit.
+func WithRateLimit(rps float64, burst int) Option {
+	return func(s *Server) {
+		s.rateLimit = rps
+		s.rateLimitBurst = burst
+	}
+}
+
+// WithClientKey sets the function identifying the client of a request for rate
+// limiting, e.g. by API key or a header set by a trusted proxy. By default
+// clients are identified by their remote IP.
+func WithClientKey(key func(r *http.Request) string) Option {
+	return func(s *Server) {
+		s.clientKey = key
+	}
+}
@@ -0,0 +1,243 @@
+// SPDX-License-Identifier: MIT
+
+// Package server exposes URL verification as a JSON API over HTTP, for
+// services which are not written in Go. Create a Server using New() and serve
+// it with an http.Server:
+//
+//	srv := server.New(server.WithVerifierOptions(urlverifier.WithHTTPCheck()))
+//	defer srv.Close()
+//	log.Fatal(http.ListenAndServe(":8080", srv))
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	urlverifier "github.com/davidmytton/url-verifier"
+	"github.com/davidmytton/url-verifier/internal/ratelimit"
+)
+
+const (
+	// DefaultMaxRequestBytes is the default maximum size of a request body
+	DefaultMaxRequestBytes = 1 << 20
+	// DefaultMaxBatchSize is the default maximum number of URLs of a batch
+	// request
+	DefaultMaxBatchSize = 100
+	// DefaultRateLimit is the default number of URLs each client may verify per
+	// second
+	DefaultRateLimit = 10
+	// DefaultRateLimitBurst is the default number of URLs each client may verify
+	// at once above the rate limit
+	DefaultRateLimitBurst = 100
+)
+
+// Server serves the verification API. It is an http.Handler with the routes:
+//
+//	POST /verify        Verify a URL: {"url": "https://example.com/"}
+//	POST /verify/batch  Verify URLs: {"urls": ["https://example.com/", ...]}
+//	GET  /healthz       Report that the server is up
+//
+// The internal IP check is enforced for all HTTP checks unless
+// WithAllowInternal() is set.
+type Server struct {
+	verifier        *urlverifier.Verifier
+	verifierOpts    []urlverifier.Option         // Options the verifier is created with (default: none)
+	allowInternal   bool                         // Whether HTTP checks to hosts that resolve to internal IPs are allowed (default: false)
+	maxRequestBytes int64                        // The maximum size of a request body (default: DefaultMaxRequestBytes)
+	maxBatchSize    int                          // The maximum number of URLs of a batch request (default: DefaultMaxBatchSize)
+	rateLimit       float64                      // The number of URLs each client may verify per second, 0 for no limit (default: DefaultRateLimit)
+	rateLimitBurst  int                          // The number of URLs each client may verify at once above the rate limit (default: DefaultRateLimitBurst)
+	clientKey       func(r *http.Request) string // Identifies the client of a request for rate limiting (default: the remote IP)
+	limiter         *ratelimit.Limiter           // The rate limiter, if rate limiting is enabled
+	mux             *http.ServeMux
+}
+
+// verifyRequest is the body of a request to /verify
+type verifyRequest struct {
+	URL string `json:"url"`
+}
+
+// batchRequest is the body of a request to /verify/batch
+type batchRequest struct {
+	URLs []string `json:"urls"`
+}
+
+// batchResponse is the body of a response from /verify/batch
+type batchResponse struct {
+	Results []*result `json:"results"`
+}
+
+// result is a verification result as returned by the API
+type result struct {
+	*urlverifier.Result
+	Error string `json:"error,omitempty"` // The error verifying the URL, if any
+}
+
+// errorResponse is the body of a response to a request which failed
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// New creates a new Server, configured with the given options
+func New(opts ...Option) *Server {
+	s := &Server{
+		maxRequestBytes: DefaultMaxRequestBytes,
+		maxBatchSize:    DefaultMaxBatchSize,
+		rateLimit:       DefaultRateLimit,
+		rateLimitBurst:  DefaultRateLimitBurst,
+		clientKey:       remoteIP,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	s.verifier = urlverifier.NewVerifier(s.verifierOpts...)
+	if !s.allowInternal {
+		s.verifier.DisallowHTTPCheckInternal()
+	}
+	if s.rateLimit > 0 {
+		s.limiter = ratelimit.New(s.rateLimit, s.rateLimitBurst)
+	}
+
+	s.mux = http.NewServeMux()
+	s.mux.HandleFunc("/verify", s.handleVerify)
+	s.mux.HandleFunc("/verify/batch", s.handleBatch)
+	s.mux.HandleFunc("/healthz", s.handleHealth)
+	return s
+}
+
+// ServeHTTP serves a request to the API
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// Close closes the idle connections pooled by the verifier
+func (s *Server) Close() error {
+	return s.verifier.Close()
+}
+
+func (s *Server) handleVerify(w http.ResponseWriter, r *http.Request) {
+	var req verifyRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if req.URL == "" {
+		writeError(w, http.StatusBadRequest, "url is required")
+		return
+	}
+	if !s.allow(w, r, 1) {
+		return
+	}
+
+	ret, err := s.verifier.VerifyContext(r.Context(), req.URL)
+	ret.Err = err
+	writeJSON(w, http.StatusOK, newResult(ret))
+}
+
+func (s *Server) handleBatch(w http.ResponseWriter, r *http.Request) {
+	var req batchRequest
+	if !s.decode(w, r, &req) {
+		return
+	}
+	if len(req.URLs) == 0 {
+		writeError(w, http.StatusBadRequest, "urls is required")
+		return
+	}
+	if s.maxBatchSize > 0 && len(req.URLs) > s.maxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d urls can be verified at once", s.maxBatchSize))
+		return
+	}
+	if !s.allow(w, r, len(req.URLs)) {
+		return
+	}
+
+	resp := batchResponse{Results: []*result{}}
+	for _, ret := range s.verifier.VerifyAll(r.Context(), req.URLs) {
+		resp.Results = append(resp.Results, newResult(ret))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// decode decodes the JSON body of a POST request into v, writing an error
+// response and returning false if it cannot
+func (s *Server) decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return false
+	}
+
+	body := r.Body
+	if s.maxRequestBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, s.maxRequestBytes)
+	}
+
+	if err := json.NewDecoder(body).Decode(v); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the request body is larger than %d bytes", maxBytesErr.Limit))
+			return false
+		}
+		writeError(w, http.StatusBadRequest, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// allow takes n tokens from the rate limit of the client of the request,
+// writing an error response and returning false if it is exceeded
+func (s *Server) allow(w http.ResponseWriter, r *http.Request, n int) bool {
+	if s.limiter == nil {
+		return true
+	}
+
+	if wait := s.limiter.Take(s.clientKey(r), n, time.Now()); wait > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "rate limit exceeded")
+		return false
+	}
+	return true
+}
+
+// newResult returns the API result of a verification result
+func newResult(ret *urlverifier.Result) *result {
+	res := &result{Result: ret}
+	if ret.Err != nil {
+		res.Error = ret.Err.Error()
+	}
+	return res
+}
+
+// remoteIP returns the IP the request was received from
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck // The client has gone away
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
@@ -0,0 +1,236 @@
+// SPDX-License-Identifier: MIT
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	urlverifier "github.com/davidmytton/url-verifier"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTarget creates a server for URLs to be verified against
+func newTarget() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+// post sends a POST request with the body to the server, decoding the JSON
+// response
+func post(t *testing.T, srv *Server, path, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("invalid JSON response %q: %s", rec.Body.String(), err)
+	}
+	return rec, resp
+}
+
+func TestVerify(t *testing.T) {
+	target := newTarget()
+	defer target.Close()
+
+	srv := New(WithVerifierOptions(
+		urlverifier.WithHTTPCheck(),
+		urlverifier.WithAllowedCIDRs(netip.MustParsePrefix("127.0.0.1/32")),
+	))
+	defer srv.Close()
+
+	rec, resp := post(t, srv, "/verify", `{"url": "`+target.URL+`/"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, target.URL+"/", resp["url"])
+	assert.Equal(t, true, resp["is_url"])
+	assert.Nil(t, resp["error"])
+	if assert.IsType(t, map[string]interface{}{}, resp["http"]) {
+		assert.Equal(t, float64(http.StatusOK), resp["http"].(map[string]interface{})["status_code"])
+	}
+}
+
+func TestVerify_InternalIPRefused(t *testing.T) {
+	target := newTarget()
+	defer target.Close()
+
+	// The internal IP check is enforced even if the verifier options allow
+	// internal IPs
+	srv := New(WithVerifierOptions(urlverifier.WithHTTPCheck(), urlverifier.WithHTTPCheckInternal()))
+	defer srv.Close()
+
+	rec, resp := post(t, srv, "/verify", `{"url": "`+target.URL+`/"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, resp["error"], "internal IP")
+	assert.NotNil(t, resp["refused"])
+}
+
+func TestVerify_AllowInternal(t *testing.T) {
+	target := newTarget()
+	defer target.Close()
+
+	srv := New(WithVerifierOptions(urlverifier.WithHTTPCheck()), WithAllowInternal())
+	defer srv.Close()
+
+	rec, resp := post(t, srv, "/verify", `{"url": "`+target.URL+`/missing"}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Nil(t, resp["error"])
+	assert.Equal(t, float64(http.StatusNotFound), resp["http"].(map[string]interface{})["status_code"])
+}
+
+func TestVerify_BadRequest(t *testing.T) {
+	srv := New(WithMaxRequestBytes(64))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		err    string
+	}{
+		{"invalid JSON", `{"url":`, http.StatusBadRequest, "invalid JSON"},
+		{"missing URL", `{}`, http.StatusBadRequest, "url is required"},
+		{"too large", `{"url": "https://example.com/` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge, "larger than 64 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, resp := post(t, srv, "/verify", tt.body)
+			assert.Equal(t, tt.status, rec.Code)
+			assert.Contains(t, resp["error"], tt.err)
+		})
+	}
+}
+
+func TestVerify_MethodNotAllowed(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/verify", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestVerifyBatch(t *testing.T) {
+	target := newTarget()
+	defer target.Close()
+
+	srv := New(WithVerifierOptions(urlverifier.WithHTTPCheck()), WithAllowInternal())
+	defer srv.Close()
+
+	rec, resp := post(t, srv, "/verify/batch", `{"urls": ["`+target.URL+`/", "not a url", "`+target.URL+`/missing"]}`)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	results := resp["results"].([]interface{})
+	if assert.Len(t, results, 3) {
+		first := results[0].(map[string]interface{})
+		assert.Equal(t, target.URL+"/", first["url"])
+		assert.Equal(t, float64(http.StatusOK), first["http"].(map[string]interface{})["status_code"])
+
+		second := results[1].(map[string]interface{})
+		assert.Equal(t, false, second["is_url"])
+		assert.Contains(t, second["error"], "HTTP or HTTPS scheme")
+
+		third := results[2].(map[string]interface{})
+		assert.Equal(t, float64(http.StatusNotFound), third["http"].(map[string]interface{})["status_code"])
+	}
+}
+
+func TestVerifyBatch_Limits(t *testing.T) {
+	srv := New(WithMaxBatchSize(2))
+	defer srv.Close()
+
+	rec, resp := post(t, srv, "/verify/batch", `{"urls": []}`)
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Equal(t, "urls is required", resp["error"])
+
+	rec, resp = post(t, srv, "/verify/batch", `{"urls": ["https://a.example/", "https://b.example/", "https://c.example/"]}`)
+	assert.Equal(t, http.StatusRequestEntityTooLarge, rec.Code)
+	assert.Equal(t, "at most 2 urls can be verified at once", resp["error"])
+}
+
+func TestRateLimit(t *testing.T) {
+	srv := New(WithRateLimit(1, 3))
+	defer srv.Close()
+
+	// A batch takes a token per URL
+	rec, _ := post(t, srv, "/verify/batch", `{"urls": ["https://a.example/", "https://b.example/"]}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, _ = post(t, srv, "/verify", `{"url": "https://a.example/"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec, resp := post(t, srv, "/verify", `{"url": "https://a.example/"}`)
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.Equal(t, "rate limit exceeded", resp["error"])
+	assert.Equal(t, "1", rec.Header().Get("Retry-After"))
+
+	// Other clients have their own limit
+	req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(`{"url": "https://a.example/"}`))
+	req.RemoteAddr = "192.0.2.2:1234"
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimit_ClientKey(t *testing.T) {
+	srv := New(WithRateLimit(1, 1), WithClientKey(func(r *http.Request) string {
+		return r.Header.Get("X-API-Key")
+	}))
+	defer srv.Close()
+
+	for _, key := range []string{"a", "b"} {
+		req := httptest.NewRequest(http.MethodPost, "/verify", strings.NewReader(`{"url": "https://a.example/"}`))
+		req.Header.Set("X-API-Key", key)
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
+
+func TestHealth(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"status": "ok"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	srv.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/healthz", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestServer_httptest(t *testing.T) {
+	srv := New()
+	defer srv.Close()
+
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/verify", "application/json", strings.NewReader(`{"url": "https://example.com/"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var ret map[string]interface{}
+	assert.Nil(t, json.NewDecoder(resp.Body).Decode(&ret))
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "https://example.com/", ret["url"])
+	assert.Equal(t, true, ret["is_url"])
+}
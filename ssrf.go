@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptrace"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// Resolver looks up the IP addresses of a host. It is satisfied by
+// *net.Resolver.
+type Resolver interface {
+	LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error)
+}
+
+// isInternalIP checks if the IP is in a special-purpose range, e.g. private,
+// loopback, link-local or a cloud metadata endpoint. See ClassifyIP.
+func isInternalIP(ip net.IP) bool {
+	return ClassifyIP(ip) != IPCategoryNone
+}
+
+// Refusal describes why the HTTP check refused to connect to a host
+type Refusal struct {
+	Host     string     `json:"host"`     // The host which was refused
+	IP       net.IP     `json:"ip"`       // The IP the host resolved to
+	Category IPCategory `json:"category"` // The category of the special-purpose range the IP is in, if it was refused as an internal IP
+	CIDR     string     `json:"cidr"`     // The denied CIDR the IP is in, if it was refused by WithDeniedCIDRs
+}
+
+// refusedIPError is returned when a host resolves to an IP refused by the IP
+// policy
+type refusedIPError struct {
+	Refusal
+}
+
+func (e *refusedIPError) Error() string {
+	if e.CIDR != "" {
+		return fmt.Sprintf("unable to check if the URL is reachable via HTTP: the URL %s resolves to IP %s in the denied CIDR %s", e.Host, e.IP, e.CIDR)
+	}
+	return fmt.Sprintf("unable to check if the URL is reachable via HTTP: the URL %s resolves to an internal IP %s (%s)", e.Host, e.IP, e.Category)
+}
+
+// Is reports whether target is ErrDeniedIP or ErrInternalIP, depending on why
+// the IP was refused
+func (e *refusedIPError) Is(target error) bool {
+	if e.CIDR != "" {
+		return target == ErrDeniedIP
+	}
+	return target == ErrInternalIP
+}
+
+// refusalFromError returns the Refusal of a refusedIPError wrapped by err, if
+// any
+func refusalFromError(err error) *Refusal {
+	var refusedErr *refusedIPError
+	if errors.As(err, &refusedErr) {
+		refusal := refusedErr.Refusal
+		return &refusal
+	}
+	return nil
+}
+
+// ipPolicyEnabled checks if the IP policy can refuse any IPs. It is disabled
+// if internal checks are allowed and no CIDRs are denied.
+func (v *Verifier) ipPolicyEnabled() bool {
+	return !v.allowHttpCheckInternal || len(v.deniedCIDRs) > 0
+}
+
+// checkIP checks an IP the host resolves to against the IP policy. Denied
+// CIDRs take precedence over allowed CIDRs, which take precedence over the
+// internal IP check.
+func (v *Verifier) checkIP(host string, ip net.IP) error {
+	addr, ok := netip.AddrFromSlice(ip)
+	if !ok {
+		return fmt.Errorf("unable to check if the URL is reachable via HTTP: unable to parse the IP %s", ip)
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range v.deniedCIDRs {
+		if prefix.Contains(addr) {
+			return &refusedIPError{Refusal{Host: host, IP: ip, CIDR: prefix.String()}}
+		}
+	}
+
+	for _, prefix := range v.allowedCIDRs {
+		if prefix.Contains(addr) {
+			return nil
+		}
+	}
+
+	if category := classifyAddr(addr); !v.allowHttpCheckInternal && category != IPCategoryNone {
+		return &refusedIPError{Refusal{Host: host, IP: ip, Category: category}}
+	}
+	return nil
+}
+
+// lookupIP resolves the host using the configured resolver, falling back to
+// the default resolver.
+func (v *Verifier) lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	if ip := net.ParseIP(host); ip != nil {
+		return []net.IP{ip}, nil
+	}
+
+	r := v.resolver
+	if r == nil {
+		r = net.DefaultResolver
+	}
+
+	// *net.Resolver reports lookups to the ClientTrace of the context itself,
+	// other resolvers are reported here
+	trace := httptrace.ContextClientTrace(ctx)
+	if _, ok := r.(*net.Resolver); ok {
+		trace = nil
+	}
+	if trace != nil && trace.DNSStart != nil {
+		trace.DNSStart(httptrace.DNSStartInfo{Host: host})
+	}
+
+	addrs, err := r.LookupIPAddr(ctx, host)
+	if trace != nil && trace.DNSDone != nil {
+		trace.DNSDone(httptrace.DNSDoneInfo{Addrs: addrs, Err: err})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// checkHost resolves the host and checks each of its IPs against the IP
+// policy.
+func (v *Verifier) checkHost(ctx context.Context, host string) ([]net.IP, error) {
+	ips, err := v.lookupIP(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+	if len(ips) == 0 {
+		return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+	}
+
+	if v.ipPolicyEnabled() {
+		for _, ip := range ips {
+			if err := v.checkIP(host, ip); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return ips, nil
+}
+
+// dialFunc is the signature of http.Transport.DialContext
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// guardDial wraps dial for use as the DialContext of the HTTP check transport.
+// The host is resolved and checked against the internal IP policy, then the
+// vetted IP is dialed directly so a second DNS lookup (e.g. DNS rebinding)
+// cannot change the address that is actually connected to. If dial is nil, a
+// net.Dialer which also checks the address in its Control function is used.
+func (v *Verifier) guardDial(dial dialFunc) dialFunc {
+	proxyDial := dial
+	if dial == nil {
+		dialer := &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}
+		proxyDial = dialer.DialContext
+
+		guardedDialer := *dialer
+		guardedDialer.Control = v.dialControl
+		dial = guardedDialer.DialContext
+	}
+
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// Connections to a proxy are not checked, the requests sent through it
+		// are checked by guardRoundTripper instead
+		if ctx.Value(proxiedKey{}) != nil {
+			return proxyDial(ctx, network, addr)
+		}
+
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		ips, err := v.checkHost(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+
+		var lastErr error
+		for _, ip := range ips {
+			conn, err := dial(ctx, network, net.JoinHostPort(ip.String(), port))
+			if err == nil {
+				return conn, nil
+			}
+			lastErr = err
+		}
+		return nil, lastErr
+	}
+}
+
+// dialControl is called after the socket is created and before it connects.
+// It checks the address being connected to against the IP policy as a final
+// guard.
+func (v *Verifier) dialControl(network, address string, c syscall.RawConn) error {
+	if !v.ipPolicyEnabled() {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("unable to check if the URL is reachable via HTTP: unable to parse the address %s", address)
+	}
+	return v.checkIP(host, ip)
+}
@@ -0,0 +1,390 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// stubResolver returns each of its responses in turn, repeating the last one
+type stubResolver struct {
+	mu        sync.Mutex
+	responses [][]string
+	calls     int
+}
+
+func (r *stubResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	i := r.calls
+	if i >= len(r.responses) {
+		i = len(r.responses) - 1
+	}
+	r.calls++
+
+	addrs := []net.IPAddr{}
+	for _, ip := range r.responses[i] {
+		addrs = append(addrs, net.IPAddr{IP: net.ParseIP(ip)})
+	}
+	return addrs, nil
+}
+
+// notFoundResolver fails every lookup as if the host does not exist
+type notFoundResolver struct{}
+
+func (r notFoundResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	return nil, &net.DNSError{Err: "no such host", Name: host, IsNotFound: true}
+}
+
+// blockingResolver blocks until the context is done
+type blockingResolver struct{}
+
+func (r blockingResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestIsInternalIP(t *testing.T) {
+	tests := []struct {
+		ip       string
+		internal bool
+	}{
+		{ip: "127.0.0.1", internal: true},
+		{ip: "10.0.0.5", internal: true},
+		{ip: "172.16.0.1", internal: true},
+		{ip: "192.168.1.1", internal: true},
+		{ip: "169.254.169.254", internal: true},
+		{ip: "0.0.0.0", internal: true},
+		{ip: "::1", internal: true},
+		{ip: "fe80::1", internal: true},
+		{ip: "fc00::1", internal: true},
+		{ip: "93.184.216.34", internal: false},
+		{ip: "2606:2800:220:1:248:1893:25c8:1946", internal: false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.internal, isInternalIP(net.ParseIP(test.ip)), test.ip)
+	}
+}
+
+func TestCheckVerify_DNSRebindingBlockedAtDial(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first lookup returns a public IP, the second returns the loopback IP
+	// the test server is listening on.
+	urlToCheck := fmt.Sprintf("http://rebind.example:%s/", tsURL.Port())
+
+	verifier := NewVerifier()
+	verifier.EnableHTTPCheck()
+	verifier.resolver = &stubResolver{responses: [][]string{{"93.184.216.34"}, {"127.0.0.1"}}}
+	ret, err := verifier.Verify(urlToCheck)
+
+	assert.Error(t, err)
+	assert.ErrorContains(t, err, "the URL rebind.example resolves to an internal IP 127.0.0.1")
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret.HTTP))
+	assert.Equal(t, 0, requests)
+}
+
+func TestCheckHTTP_InternalIPBlockedAtDial(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier()
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	var urlErr *url.Error
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.ErrorAs(t, err, &urlErr)
+	assert.ErrorIs(t, err, ErrInternalIP)
+	assert.ErrorContains(t, err, "resolves to an internal IP 127.0.0.1")
+}
+
+func TestCheckHTTP_InternalIPAllowedAtDial(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urlToCheck := fmt.Sprintf("http://internal.example:%s/", tsURL.Port())
+
+	verifier := NewVerifier()
+	verifier.AllowHTTPCheckInternal()
+	verifier.resolver = &stubResolver{responses: [][]string{{"127.0.0.1"}}}
+	ret, err := verifier.CheckHTTP(urlToCheck)
+
+	expected := &HTTP{
+		Reachable:  true,
+		StatusCode: 200,
+		IsSuccess:  true,
+		FinalURL:   urlToCheck,
+		Method:     "GET",
+	}
+
+	assert.Equal(t, expected, withoutTiming(ret))
+	assert.Nil(t, err)
+}
+
+func TestDialControl(t *testing.T) {
+	verifier := NewVerifier()
+
+	assert.NoError(t, verifier.dialControl("tcp4", "93.184.216.34:80", nil))
+	assert.ErrorContains(t, verifier.dialControl("tcp4", "127.0.0.1:80", nil), "resolves to an internal IP 127.0.0.1")
+	assert.ErrorContains(t, verifier.dialControl("tcp6", "[::1]:80", nil), "resolves to an internal IP ::1")
+
+	verifier.AllowHTTPCheckInternal()
+	assert.NoError(t, verifier.dialControl("tcp4", "127.0.0.1:80", nil))
+}
+
+func TestCheckRedirect(t *testing.T) {
+	tests := []struct {
+		url      string
+		resolves []string
+		err      string
+	}{
+		{url: "http://10.0.0.5/admin", err: "refused to follow redirect 1 to http://10.0.0.5/admin: unable to check if the URL is reachable via HTTP: the URL 10.0.0.5 resolves to an internal IP 10.0.0.5"},
+		{url: "http://169.254.169.254/latest/meta-data", err: "resolves to an internal IP 169.254.169.254"},
+		{url: "http://metadata.example/", resolves: []string{"169.254.169.254"}, err: "the URL metadata.example resolves to an internal IP 169.254.169.254"},
+		{url: "ftp://example.com/", err: "refused to follow redirect 1 to ftp://example.com/: the URL does not have a HTTP or HTTPS scheme"},
+		{url: "https://www.example.com/", resolves: []string{"93.184.216.34"}},
+	}
+
+	for _, test := range tests {
+		verifier := NewVerifier()
+		verifier.resolver = &stubResolver{responses: [][]string{test.resolves}}
+
+		via, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		req, _ := http.NewRequest(http.MethodGet, test.url, nil)
+		err := verifier.checkRedirect(req, []*http.Request{via})
+
+		if test.err == "" {
+			assert.NoError(t, err, test.url)
+			continue
+		}
+
+		var redirectErr *RedirectError
+		assert.True(t, errors.As(err, &redirectErr), test.url)
+		assert.Equal(t, 1, redirectErr.Hop)
+		assert.Equal(t, test.url, redirectErr.URL)
+		assert.ErrorContains(t, err, test.err)
+	}
+}
+
+func TestCheckRedirect_InternalAllowed(t *testing.T) {
+	verifier := NewVerifier()
+	verifier.AllowHTTPCheckInternal()
+
+	via, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+	req, _ := http.NewRequest(http.MethodGet, "http://10.0.0.5/admin", nil)
+
+	assert.NoError(t, verifier.checkRedirect(req, []*http.Request{via}))
+}
+
+func TestCheckRedirect_TooMany(t *testing.T) {
+	verifier := NewVerifier()
+
+	via := []*http.Request{}
+	for i := 0; i <= DefaultMaxRedirects; i++ {
+		r, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+		via = append(via, r)
+	}
+	req, _ := http.NewRequest(http.MethodGet, "https://example.com/", nil)
+
+	assert.ErrorContains(t, verifier.checkRedirect(req, via), "stopped after 10 redirects")
+}
+
+func TestCheckHTTP_RedirectToNonHTTPScheme(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "ftp://example.com/file", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier()
+	verifier.AllowHTTPCheckInternal()
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	var redirectErr *RedirectError
+	var urlErr *url.Error
+	assert.False(t, ret.Reachable)
+	assert.Len(t, ret.Redirects, 1)
+	assert.ErrorAs(t, err, &urlErr)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+	assert.True(t, errors.As(err, &redirectErr))
+	assert.Equal(t, 1, redirectErr.Hop)
+	assert.Equal(t, "ftp://example.com/file", redirectErr.URL)
+}
+
+func TestCheckVerify_ResolverUsedForCheckAndDial(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urlToCheck := fmt.Sprintf("http://resolver.example:%s/", tsURL.Port())
+
+	resolver := &stubResolver{responses: [][]string{{"127.0.0.1"}}}
+	verifier := NewVerifier(WithHTTPCheck(), WithResolver(resolver))
+	ret, err := verifier.Verify(urlToCheck)
+
+	assert.ErrorContains(t, err, "the URL resolver.example resolves to an internal IP 127.0.0.1")
+	assert.Nil(t, ret.HTTP)
+	assert.Equal(t, 1, resolver.calls)
+
+	resolver = &stubResolver{responses: [][]string{{"127.0.0.1"}}}
+	verifier = NewVerifier(WithHTTPCheck(), WithHTTPCheckInternal(), WithResolver(resolver))
+	ret, err = verifier.Verify(urlToCheck)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: urlToCheck}, withoutTiming(ret.HTTP))
+	assert.Equal(t, 1, resolver.calls)
+}
+
+func TestLookupIP_NilResolver(t *testing.T) {
+	// Verifiers not created with NewVerifier fall back to net.DefaultResolver,
+	// which resolves localhost without querying a DNS server
+	verifier := &Verifier{}
+	ips, err := verifier.lookupIP(context.Background(), "localhost")
+
+	assert.Nil(t, err)
+	assert.NotEmpty(t, ips)
+	for _, ip := range ips {
+		assert.True(t, ip.IsLoopback(), ip.String())
+	}
+}
+
+func TestCheckIP_CIDRs(t *testing.T) {
+	verifier := NewVerifier(
+		WithAllowedCIDRs(netip.MustParsePrefix("10.20.0.0/16"), netip.MustParsePrefix("203.0.113.0/24")),
+		WithDeniedCIDRs(netip.MustParsePrefix("203.0.113.128/25"), netip.MustParsePrefix("198.51.100.7/24")),
+	)
+
+	tests := []struct {
+		ip  string
+		err string
+	}{
+		{ip: "10.20.1.1"},
+		{ip: "::ffff:10.20.1.1"},
+		{ip: "10.21.1.1", err: "the URL host.example resolves to an internal IP 10.21.1.1"},
+		{ip: "203.0.113.1"},
+		{ip: "203.0.113.200", err: "the URL host.example resolves to IP 203.0.113.200 in the denied CIDR 203.0.113.128/25"},
+		{ip: "198.51.100.1", err: "the URL host.example resolves to IP 198.51.100.1 in the denied CIDR 198.51.100.0/24"},
+		{ip: "93.184.216.34"},
+	}
+
+	for _, test := range tests {
+		err := verifier.checkIP("host.example", net.ParseIP(test.ip))
+		if test.err == "" {
+			assert.NoError(t, err, test.ip)
+		} else {
+			assert.ErrorContains(t, err, test.err, test.ip)
+		}
+	}
+}
+
+func TestCheckIP_DeniedWithInternalAllowed(t *testing.T) {
+	verifier := NewVerifier(
+		WithHTTPCheckInternal(),
+		WithDeniedCIDRs(netip.MustParsePrefix("169.254.169.254/32")),
+	)
+
+	assert.True(t, verifier.ipPolicyEnabled())
+	assert.NoError(t, verifier.checkIP("host.example", net.ParseIP("10.0.0.5")))
+	assert.ErrorContains(t, verifier.checkIP("host.example", net.ParseIP("169.254.169.254")), "in the denied CIDR 169.254.169.254/32")
+}
+
+func TestCheckVerify_AllowedCIDR(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheck(), WithAllowedCIDRs(netip.MustParsePrefix("127.0.0.1/32")))
+	ret, err := verifier.Verify(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: ts.URL}, withoutTiming(ret.HTTP))
+}
+
+func TestCheckVerify_DeniedCIDR(t *testing.T) {
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithResolver(&stubResolver{responses: [][]string{{"198.51.100.7"}}}),
+		WithDeniedCIDRs(netip.MustParsePrefix("198.51.100.0/24")),
+	)
+	ret, err := verifier.Verify("https://partner.example/")
+
+	assert.ErrorContains(t, err, "the URL partner.example resolves to IP 198.51.100.7 in the denied CIDR 198.51.100.0/24")
+	assert.Nil(t, ret.HTTP)
+}
+
+func TestCheckHTTP_RedirectToInternalIP(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://10.0.0.5/admin", http.StatusFound)
+	}))
+	defer ts.Close()
+
+	// The test server is allowed, the internal IP it redirects to is not
+	verifier := NewVerifier(WithAllowedCIDRs(netip.MustParsePrefix("127.0.0.1/32")))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	var redirectErr *RedirectError
+	assert.False(t, ret.Reachable)
+	assert.Len(t, ret.Redirects, 1)
+	assert.True(t, errors.As(err, &redirectErr))
+	assert.Equal(t, 1, redirectErr.Hop)
+	assert.Equal(t, "http://10.0.0.5/admin", redirectErr.URL)
+	assert.ErrorContains(t, err, "resolves to an internal IP 10.0.0.5")
+}
+
+func TestCheckVerify_RefusedOnResult(t *testing.T) {
+	verifier := NewVerifier(
+		WithHTTPCheck(),
+		WithResolver(&stubResolver{responses: [][]string{{"100.64.1.1"}}}),
+	)
+	ret, err := verifier.Verify("https://cgnat.example/")
+
+	assert.ErrorContains(t, err, "the URL cgnat.example resolves to an internal IP 100.64.1.1 (cgnat)")
+	assert.Equal(t, &Refusal{Host: "cgnat.example", IP: net.ParseIP("100.64.1.1"), Category: IPCategoryCGNAT}, ret.Refused)
+
+	// Refusals when dialing are also reported
+	verifier = NewVerifier(
+		WithHTTPCheck(),
+		WithResolver(&stubResolver{responses: [][]string{{"93.184.216.34"}, {"::ffff:169.254.169.254"}}}),
+	)
+	ret, err = verifier.Verify("http://rebind.example/")
+
+	assert.ErrorContains(t, err, "resolves to an internal IP 169.254.169.254 (metadata)")
+	assert.Equal(t, &Refusal{Host: "rebind.example", IP: net.ParseIP("169.254.169.254"), Category: IPCategoryMetadata}, ret.Refused)
+}
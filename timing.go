@@ -0,0 +1,120 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing is the duration of each phase of a HTTP check. Phases which happen
+// more than once, e.g. when following redirects, are summed.
+type Timing struct {
+	DNS          time.Duration `json:"dns"`           // Resolving hosts
+	Connect      time.Duration `json:"connect"`       // Connecting to hosts
+	TLSHandshake time.Duration `json:"tls_handshake"` // TLS handshakes
+	TTFB         time.Duration `json:"ttfb"`          // From sending the final request to receiving the first byte of its response
+	Total        time.Duration `json:"total"`         // From starting the check to receiving the final response headers, or failing
+	ConnReused   bool          `json:"conn_reused"`   // Whether the final request was sent on a pooled connection
+}
+
+// timingRecorder records the timing of a HTTP check using httptrace hooks
+type timingRecorder struct {
+	mu           sync.Mutex
+	start        time.Time // When the check started
+	dnsStart     time.Time // When the current DNS lookup started, zero if none is in progress
+	connectStart time.Time // When the current connection attempt started
+	tlsStart     time.Time // When the current TLS handshake started
+	wroteRequest time.Time // When the current request was written
+	timing       Timing
+}
+
+func newTimingRecorder() *timingRecorder {
+	return &timingRecorder{start: time.Now()}
+}
+
+// trace returns a ClientTrace which updates the recorder
+func (r *timingRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			r.update(func(now time.Time) {
+				r.dnsStart = now
+			})
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			r.update(func(now time.Time) {
+				if !r.dnsStart.IsZero() {
+					r.timing.DNS += now.Sub(r.dnsStart)
+					r.dnsStart = time.Time{}
+				}
+			})
+		},
+		// Connections to DNS servers made during a lookup are ignored, and
+		// only the first of concurrent connection attempts is timed
+		ConnectStart: func(network, addr string) {
+			r.update(func(now time.Time) {
+				if r.dnsStart.IsZero() && r.connectStart.IsZero() {
+					r.connectStart = now
+				}
+			})
+		},
+		ConnectDone: func(network, addr string, err error) {
+			r.update(func(now time.Time) {
+				if r.dnsStart.IsZero() && !r.connectStart.IsZero() {
+					r.timing.Connect += now.Sub(r.connectStart)
+					r.connectStart = time.Time{}
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			r.update(func(now time.Time) {
+				r.tlsStart = now
+			})
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			r.update(func(now time.Time) {
+				if !r.tlsStart.IsZero() {
+					r.timing.TLSHandshake += now.Sub(r.tlsStart)
+					r.tlsStart = time.Time{}
+				}
+			})
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			r.update(func(now time.Time) {
+				r.timing.ConnReused = info.Reused
+			})
+		},
+		WroteRequest: func(info httptrace.WroteRequestInfo) {
+			r.update(func(now time.Time) {
+				r.wroteRequest = now
+			})
+		},
+		GotFirstResponseByte: func() {
+			r.update(func(now time.Time) {
+				if !r.wroteRequest.IsZero() {
+					r.timing.TTFB = now.Sub(r.wroteRequest)
+				}
+			})
+		},
+	}
+}
+
+func (r *timingRecorder) update(f func(now time.Time)) {
+	now := time.Now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f(now)
+}
+
+// finish returns the timing of the check, which ends now
+func (r *timingRecorder) finish() *Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	timing := r.timing
+	timing.Total = time.Since(r.start)
+	return &timing
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// delayedResolver delays lookups by the next resolver
+type delayedResolver struct {
+	delay time.Duration
+	next  Resolver
+}
+
+func (r *delayedResolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	time.Sleep(r.delay)
+	return r.next.LookupIPAddr(ctx, host)
+}
+
+func TestCheckHTTP_Timing(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	urlToCheck := fmt.Sprintf("https://example.com:%s/", tsURL.Port())
+
+	verifier := NewVerifier(
+		WithHTTPCheckInternal(),
+		WithTransport(&http.Transport{TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone()}),
+		WithResolver(&delayedResolver{delay: 10 * time.Millisecond, next: &stubResolver{responses: [][]string{{"127.0.0.1"}}}}),
+	)
+	defer verifier.Close()
+
+	ret, err := verifier.CheckHTTP(urlToCheck)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, ret.Timing) {
+		assert.GreaterOrEqual(t, ret.Timing.DNS, 10*time.Millisecond)
+		assert.Greater(t, ret.Timing.Connect, time.Duration(0))
+		assert.Greater(t, ret.Timing.TLSHandshake, time.Duration(0))
+		assert.GreaterOrEqual(t, ret.Timing.TTFB, 20*time.Millisecond)
+		assert.GreaterOrEqual(t, ret.Timing.Total, ret.Timing.DNS+ret.Timing.Connect+ret.Timing.TLSHandshake+ret.Timing.TTFB)
+		assert.False(t, ret.Timing.ConnReused)
+	}
+
+	// The pooled connection is reused, so there is no lookup, connection or
+	// handshake
+	ret, err = verifier.CheckHTTP(urlToCheck)
+
+	assert.Nil(t, err)
+	if assert.NotNil(t, ret.Timing) {
+		assert.Equal(t, time.Duration(0), ret.Timing.DNS)
+		assert.Equal(t, time.Duration(0), ret.Timing.Connect)
+		assert.Equal(t, time.Duration(0), ret.Timing.TLSHandshake)
+		assert.GreaterOrEqual(t, ret.Timing.TTFB, 20*time.Millisecond)
+		assert.True(t, ret.Timing.ConnReused)
+	}
+}
+
+func TestCheckHTTP_TimingRedirects(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			http.Redirect(w, r, "/final", http.StatusFound)
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL + "/")
+
+	// The redirect is followed on the same connection, and the TTFB is that of
+	// the final response
+	assert.Nil(t, err)
+	if assert.NotNil(t, ret.Timing) {
+		assert.Greater(t, ret.Timing.Connect, time.Duration(0))
+		assert.Equal(t, time.Duration(0), ret.Timing.TLSHandshake)
+		assert.GreaterOrEqual(t, ret.Timing.TTFB, 20*time.Millisecond)
+		assert.True(t, ret.Timing.ConnReused)
+	}
+}
+
+func TestCheckHTTP_TimingFailure(t *testing.T) {
+	verifier := NewVerifier(WithResolver(&delayedResolver{delay: 10 * time.Millisecond, next: notFoundResolver{}}))
+	ret, err := verifier.CheckHTTP("http://example.unreachable")
+
+	assert.ErrorIs(t, err, ErrDNSFailure)
+	if assert.NotNil(t, ret.Timing) {
+		assert.GreaterOrEqual(t, ret.Timing.DNS, 10*time.Millisecond)
+		assert.GreaterOrEqual(t, ret.Timing.Total, ret.Timing.DNS)
+	}
+}
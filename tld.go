@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+)
+
+//go:generate curl -fsSL -o data/tlds-alpha-by-domain.txt https://data.iana.org/TLD/tlds-alpha-by-domain.txt
+
+// tldListData is the embedded list of top-level domains
+//
+//go:embed data/tlds-alpha-by-domain.txt
+var tldListData []byte
+
+var (
+	defaultTLDListOnce sync.Once
+	defaultTLDList     *TLDList
+)
+
+// TLDList is a list of the top-level domains in the DNS root zone. Create one
+// using ParseTLDList() or use DefaultTLDList(). It is safe for concurrent use.
+type TLDList struct {
+	tlds map[string]struct{} // The lowercased TLDs, with non-ASCII TLDs encoded with Punycode
+}
+
+// ParseTLDList parses a list of TLDs in the format of
+// https://data.iana.org/TLD/tlds-alpha-by-domain.txt: a TLD per line, with
+// lines starting with "#" ignored
+func ParseTLDList(r io.Reader) (*TLDList, error) {
+	l := &TLDList{tlds: map[string]struct{}{}}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.tlds[toASCII(strings.TrimPrefix(line, "."))] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(l.tlds) == 0 {
+		return nil, fmt.Errorf("the TLD list has no TLDs")
+	}
+	return l, nil
+}
+
+// DefaultTLDList returns the TLD list embedded in the package, parsing it on
+// first use
+func DefaultTLDList() *TLDList {
+	defaultTLDListOnce.Do(func() {
+		list, err := ParseTLDList(bytes.NewReader(tldListData))
+		if err != nil {
+			panic(fmt.Sprintf("urlverifier: parsing the embedded TLD list: %s", err))
+		}
+		defaultTLDList = list
+	})
+	return defaultTLDList
+}
+
+// Contains reports whether the TLD is in the list. The TLD is matched
+// case-insensitively and may be Unicode or encoded with Punycode.
+func (l *TLDList) Contains(tld string) bool {
+	_, ok := l.tlds[toASCII(strings.TrimPrefix(tld, "."))]
+	return ok
+}
+
+// HasKnownTLD reports whether the host is a domain name with a TLD in the list.
+// IPs do not have a TLD.
+func (l *TLDList) HasKnownTLD(host string) bool {
+	host = strings.TrimSuffix(host, ".")
+	if host == "" || net.ParseIP(host) != nil {
+		return false
+	}
+	return l.Contains(host[strings.LastIndexByte(host, '.')+1:])
+}
+
+// Len returns the number of TLDs in the list
+func (l *TLDList) Len() int {
+	return len(l.tlds)
+}
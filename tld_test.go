@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTLDList_HasKnownTLD(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected bool
+	}{
+		{"example.com", true},
+		{"WWW.Example.COM.", true},
+		{"example.dev", true},
+		{"example.co.uk", true},
+		{"example.中国", true},
+		{"example.xn--fiqs8s", true},
+		{"com", true},
+		{"example.cmo", false},
+		{"example.notatld", false},
+		{"example.onion", false},
+		{"localhost", false},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			assert.Equal(t, tt.expected, DefaultTLDList().HasKnownTLD(tt.host))
+		})
+	}
+}
+
+func TestTLDList_Contains(t *testing.T) {
+	list := DefaultTLDList()
+	assert.Greater(t, list.Len(), 1000)
+	assert.True(t, list.Contains("com"))
+	assert.True(t, list.Contains("COM"))
+	assert.True(t, list.Contains(".com"))
+	assert.True(t, list.Contains("中国"))
+	assert.True(t, list.Contains("XN--FIQS8S"))
+	assert.False(t, list.Contains("cmo"))
+	assert.False(t, list.Contains(""))
+}
+
+func TestParseTLDList(t *testing.T) {
+	list, err := ParseTLDList(strings.NewReader(`# Version 2023020100
+EXAMPLE
+
+XN--FIQS8S
+`))
+
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, list.Len())
+		assert.True(t, list.HasKnownTLD("site.example"))
+		assert.True(t, list.HasKnownTLD("site.中国"))
+		assert.False(t, list.HasKnownTLD("example.com"))
+	}
+
+	_, err = ParseTLDList(strings.NewReader("# No TLDs\n"))
+	assert.EqualError(t, err, "the TLD list has no TLDs")
+}
+
+func TestVerify_RequireKnownTLD(t *testing.T) {
+	verifier := NewVerifier(WithRequireKnownTLD())
+
+	ret, err := verifier.Verify("https://example.com/")
+	assert.Nil(t, err)
+	assert.True(t, ret.HasKnownTLD)
+	assert.True(t, ret.IsURL)
+
+	ret, err = verifier.Verify("https://example.cmo/")
+	assert.Nil(t, err)
+	assert.False(t, ret.HasKnownTLD)
+	assert.False(t, ret.IsURL)
+
+	// IPs do not have a TLD, so are not affected
+	ret, err = verifier.Verify("http://127.0.0.1/")
+	assert.Nil(t, err)
+	assert.False(t, ret.HasKnownTLD)
+	assert.True(t, ret.IsURL)
+
+	// Without the option, an unknown TLD does not make a URL invalid
+	ret, err = NewVerifier().Verify("https://example.cmo/")
+	assert.Nil(t, err)
+	assert.False(t, ret.HasKnownTLD)
+	assert.True(t, ret.IsURL)
+}
+
+func TestVerify_RequireKnownTLD_HTTPCheck(t *testing.T) {
+	verifier := NewVerifier(WithHTTPCheck(), WithRequireKnownTLD(), WithResolver(blockingResolver{}))
+
+	ret, err := verifier.Verify("https://example.cmo/")
+	assert.ErrorIs(t, err, ErrUnknownTLD)
+	assert.False(t, ret.IsURL)
+	assert.Nil(t, ret.HTTP)
+
+	var verr *Error
+	if assert.ErrorAs(t, err, &verr) {
+		assert.Equal(t, PhaseParse, verr.Phase)
+		assert.Equal(t, "example.cmo", verr.Host)
+	}
+}
+
+func TestVerify_TLDList(t *testing.T) {
+	list, err := ParseTLDList(strings.NewReader("INTERNAL\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewVerifier(WithTLDList(list), WithRequireKnownTLD())
+
+	ret, err := verifier.Verify("https://intranet.internal/")
+	assert.Nil(t, err)
+	assert.True(t, ret.HasKnownTLD)
+	assert.True(t, ret.IsURL)
+
+	ret, err = verifier.Verify("https://example.com/")
+	assert.Nil(t, err)
+	assert.False(t, ret.HasKnownTLD)
+	assert.False(t, ret.IsURL)
+}
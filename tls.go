@@ -0,0 +1,232 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// TLSFailure is the reason the certificate of a HTTPS URL failed verification
+type TLSFailure string
+
+const (
+	TLSFailureNone             TLSFailure = ""                  // The certificate is valid
+	TLSFailureExpired          TLSFailure = "expired"           // The certificate has expired
+	TLSFailureNotYetValid      TLSFailure = "not_yet_valid"     // The certificate is not valid yet
+	TLSFailureSelfSigned       TLSFailure = "self_signed"       // The certificate is self-signed and not trusted
+	TLSFailureHostnameMismatch TLSFailure = "hostname_mismatch" // The certificate is not valid for the host
+	TLSFailureUnknownAuthority TLSFailure = "unknown_authority" // The certificate is signed by an untrusted authority
+	TLSFailureOther            TLSFailure = "other"             // The certificate or handshake is invalid for another reason
+)
+
+// TLS is the result of inspecting the TLS connection and certificate of a
+// HTTPS URL
+type TLS struct {
+	Version         string     `json:"version"`           // The negotiated TLS version, e.g. TLS 1.3
+	CipherSuite     string     `json:"cipher_suite"`      // The negotiated cipher suite
+	ALPN            string     `json:"alpn"`              // The negotiated application protocol, e.g. h2, if any
+	Subject         string     `json:"subject"`           // The subject of the leaf certificate
+	Issuer          string     `json:"issuer"`            // The issuer of the leaf certificate
+	SANs            []string   `json:"sans"`              // The DNS names and IPs the leaf certificate is valid for
+	NotBefore       time.Time  `json:"not_before"`        // When the leaf certificate becomes valid
+	NotAfter        time.Time  `json:"not_after"`         // When the leaf certificate expires
+	DaysUntilExpiry int        `json:"days_until_expiry"` // The number of whole days until the leaf certificate expires, negative once expired
+	ChainValid      bool       `json:"chain_valid"`       // Whether the certificate chain is trusted and valid for the host
+	Failure         TLSFailure `json:"failure"`           // Why the certificate failed verification, if it did
+}
+
+// ExpiresWithin reports whether the leaf certificate expires within d
+func (t *TLS) ExpiresWithin(d time.Duration) bool {
+	return !t.NotAfter.IsZero() && time.Until(t.NotAfter) < d
+}
+
+// tlsVersionNames are the names of the TLS versions supported by crypto/tls
+var tlsVersionNames = map[uint16]string{
+	tls.VersionTLS10: "TLS 1.0",
+	tls.VersionTLS11: "TLS 1.1",
+	tls.VersionTLS12: "TLS 1.2",
+	tls.VersionTLS13: "TLS 1.3",
+}
+
+// tlsRecorder records the state of the last TLS handshake of a request, so it
+// can be inspected when the handshake fails
+type tlsRecorder struct {
+	mu    sync.Mutex
+	state *tls.ConnectionState
+}
+
+// trace returns a ClientTrace which updates the recorder
+func (r *tlsRecorder) trace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+
+			r.state = &state
+		},
+	}
+}
+
+func (r *tlsRecorder) get() *tls.ConnectionState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state
+}
+
+// inspectTLS inspects the TLS connection of a response from host. If the
+// certificate was not verified because verification is skipped, it is verified
+// here so the result still describes whether it is valid.
+func (v *Verifier) inspectTLS(state *tls.ConnectionState, host string) *TLS {
+	ret := newTLS(state)
+	if len(state.PeerCertificates) == 0 {
+		return ret
+	}
+	ret.setLeaf(state.PeerCertificates[0])
+
+	if len(state.VerifiedChains) > 0 {
+		ret.ChainValid = true
+		return ret
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         v.rootCAs(),
+		Intermediates: intermediates,
+		DNSName:       host,
+	})
+	if err != nil {
+		ret.Failure, _ = tlsFailure(err, host, state.PeerCertificates[0])
+		return ret
+	}
+	ret.ChainValid = true
+	return ret
+}
+
+// inspectTLSError inspects a failed TLS handshake with host, returning nil if
+// err is not a TLS error. The peer certificates are not available when the
+// handshake fails, so the leaf certificate is only described if it is the
+// certificate which failed verification.
+func inspectTLSError(state *tls.ConnectionState, host string, err error) *TLS {
+	if errorKind(err) != ErrTLS {
+		return nil
+	}
+
+	ret := &TLS{}
+	if state != nil {
+		ret = newTLS(state)
+	}
+
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		if u, parseErr := url.Parse(urlErr.URL); parseErr == nil {
+			host = u.Hostname()
+		}
+	}
+
+	var cert *x509.Certificate
+	ret.Failure, cert = tlsFailure(err, host, nil)
+	if cert != nil && isLeaf(cert, host) {
+		ret.setLeaf(cert)
+	}
+	return ret
+}
+
+// newTLS returns the negotiated parameters of a TLS connection
+func newTLS(state *tls.ConnectionState) *TLS {
+	ret := &TLS{
+		Version: tlsVersionNames[state.Version],
+		ALPN:    state.NegotiatedProtocol,
+	}
+	if ret.Version == "" && state.Version != 0 {
+		ret.Version = fmt.Sprintf("0x%04x", state.Version)
+	}
+	if state.CipherSuite != 0 {
+		ret.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+	}
+	return ret
+}
+
+// setLeaf sets the details of the leaf certificate
+func (t *TLS) setLeaf(cert *x509.Certificate) {
+	t.Subject = cert.Subject.String()
+	t.Issuer = cert.Issuer.String()
+	t.SANs = append([]string{}, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		t.SANs = append(t.SANs, ip.String())
+	}
+	t.NotBefore = cert.NotBefore
+	t.NotAfter = cert.NotAfter
+	t.DaysUntilExpiry = int(math.Floor(time.Until(cert.NotAfter).Hours() / 24))
+}
+
+// tlsFailure categorizes a certificate verification error for host, returning
+// the certificate which failed verification if known. The leaf certificate is
+// used to tell self-signed certificates apart, or if nil the certificate which
+// failed verification if it is the leaf.
+func tlsFailure(err error, host string, leaf *x509.Certificate) (TLSFailure, *x509.Certificate) {
+	var certInvalidErr x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var unknownAuthorityErr x509.UnknownAuthorityError
+
+	switch {
+	case errors.As(err, &certInvalidErr):
+		if certInvalidErr.Reason != x509.Expired {
+			return TLSFailureOther, certInvalidErr.Cert
+		}
+		if certInvalidErr.Cert != nil && time.Now().Before(certInvalidErr.Cert.NotBefore) {
+			return TLSFailureNotYetValid, certInvalidErr.Cert
+		}
+		return TLSFailureExpired, certInvalidErr.Cert
+	case errors.As(err, &hostnameErr):
+		return TLSFailureHostnameMismatch, hostnameErr.Certificate
+	case errors.As(err, &unknownAuthorityErr):
+		if leaf == nil && unknownAuthorityErr.Cert != nil && isLeaf(unknownAuthorityErr.Cert, host) {
+			leaf = unknownAuthorityErr.Cert
+		}
+		if isSelfSigned(leaf) {
+			return TLSFailureSelfSigned, unknownAuthorityErr.Cert
+		}
+		return TLSFailureUnknownAuthority, unknownAuthorityErr.Cert
+	}
+	return TLSFailureOther, nil
+}
+
+// isLeaf reports whether the certificate is a leaf certificate for host, rather
+// than a CA certificate in its chain
+func isLeaf(cert *x509.Certificate, host string) bool {
+	return !cert.IsCA || cert.VerifyHostname(host) == nil
+}
+
+// isSelfSigned reports whether the certificate is signed by its own key
+func isSelfSigned(cert *x509.Certificate) bool {
+	if cert == nil || !bytes.Equal(cert.RawIssuer, cert.RawSubject) {
+		return false
+	}
+	return cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// rootCAs returns the root certificates trusted by the transport set with
+// WithTransport or WithHTTPClient, or nil for the system roots
+func (v *Verifier) rootCAs() *x509.CertPool {
+	rt := v.transport
+	if rt == nil && v.httpClient != nil {
+		rt = v.httpClient.Transport
+	}
+	if t, ok := rt.(*http.Transport); ok && t.TLSClientConfig != nil {
+		return t.TLSClientConfig.RootCAs
+	}
+	return nil
+}
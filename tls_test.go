@@ -0,0 +1,254 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testCert is a certificate and its key, generated for a test
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newTestCert creates a certificate from the template, signed by the parent or
+// self-signed if the parent is nil
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCert{cert: cert, key: key}
+}
+
+// newTestCA creates a self-signed CA certificate
+func newTestCA(t *testing.T) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "Test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(365 * 24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}, nil)
+}
+
+// newTestLeaf creates a leaf certificate for example.test and 127.0.0.1 valid
+// between notBefore and notAfter
+func newTestLeaf(t *testing.T, notBefore, notAfter time.Time, parent *testCert) *testCert {
+	return newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "example.test"},
+		NotBefore:   notBefore,
+		NotAfter:    notAfter,
+		KeyUsage:    x509.KeyUsageDigitalSignature,
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:    []string{"example.test"},
+		IPAddresses: []net.IP{net.IPv4(127, 0, 0, 1)},
+	}, parent)
+}
+
+// newTestTLSServer starts a HTTPS server presenting the leaf certificate and
+// its chain
+func newTestTLSServer(t *testing.T, leaf *testCert, chain ...*testCert) *httptest.Server {
+	t.Helper()
+
+	certificate := tls.Certificate{Certificate: [][]byte{leaf.cert.Raw}, PrivateKey: leaf.key}
+	for _, c := range chain {
+		certificate.Certificate = append(certificate.Certificate, c.cert.Raw)
+	}
+
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	ts.EnableHTTP2 = true
+	ts.TLS = &tls.Config{Certificates: []tls.Certificate{certificate}}
+	ts.StartTLS()
+	return ts
+}
+
+// newTrustingTransport returns a transport which trusts the CA
+func newTrustingTransport(ca *testCert) *http.Transport {
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	return &http.Transport{
+		ForceAttemptHTTP2: true,
+		TLSClientConfig:   &tls.Config{RootCAs: roots},
+	}
+}
+
+func TestCheckHTTP_TLSValid(t *testing.T) {
+	ca := newTestCA(t)
+	leaf := newTestLeaf(t, time.Now().Add(-time.Hour), time.Now().Add(10*24*time.Hour+time.Hour), ca)
+
+	ts := newTestTLSServer(t, leaf, ca)
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal(), WithTransport(newTrustingTransport(ca)))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	if assert.NotNil(t, ret.TLS) {
+		assert.Equal(t, "TLS 1.3", ret.TLS.Version)
+		assert.NotEmpty(t, ret.TLS.CipherSuite)
+		assert.Equal(t, "h2", ret.TLS.ALPN)
+		assert.Equal(t, "CN=example.test", ret.TLS.Subject)
+		assert.Equal(t, "CN=Test CA", ret.TLS.Issuer)
+		assert.Equal(t, []string{"example.test", "127.0.0.1"}, ret.TLS.SANs)
+		assert.Equal(t, leaf.cert.NotBefore, ret.TLS.NotBefore)
+		assert.Equal(t, leaf.cert.NotAfter, ret.TLS.NotAfter)
+		assert.Equal(t, 10, ret.TLS.DaysUntilExpiry)
+		assert.True(t, ret.TLS.ExpiresWithin(14*24*time.Hour))
+		assert.False(t, ret.TLS.ExpiresWithin(7*24*time.Hour))
+		assert.True(t, ret.TLS.ChainValid)
+		assert.Equal(t, TLSFailureNone, ret.TLS.Failure)
+	}
+}
+
+func TestCheckHTTP_TLSFailure(t *testing.T) {
+	ca := newTestCA(t)
+	untrustedCA := newTestCA(t)
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		leaf     *testCert
+		chain    []*testCert
+		url      func(ts *httptest.Server) string
+		failure  TLSFailure
+		subject  string // The leaf subject, if known on failure
+		daysLeft int
+	}{
+		{
+			name:     "expired",
+			leaf:     newTestLeaf(t, now.Add(-48*time.Hour), now.Add(-36*time.Hour), ca),
+			chain:    []*testCert{ca},
+			failure:  TLSFailureExpired,
+			subject:  "CN=example.test",
+			daysLeft: -2,
+		},
+		{
+			name:     "not yet valid",
+			leaf:     newTestLeaf(t, now.Add(24*time.Hour), now.Add(48*time.Hour+time.Hour), ca),
+			chain:    []*testCert{ca},
+			failure:  TLSFailureNotYetValid,
+			subject:  "CN=example.test",
+			daysLeft: 2,
+		},
+		{
+			name:     "self-signed",
+			leaf:     newTestLeaf(t, now.Add(-time.Hour), now.Add(24*time.Hour+time.Hour), nil),
+			failure:  TLSFailureSelfSigned,
+			subject:  "CN=example.test",
+			daysLeft: 1,
+		},
+		{
+			name:    "unknown authority",
+			leaf:    newTestLeaf(t, now.Add(-time.Hour), now.Add(24*time.Hour+time.Hour), untrustedCA),
+			chain:   []*testCert{untrustedCA},
+			failure: TLSFailureUnknownAuthority,
+		},
+		{
+			name:  "hostname mismatch",
+			leaf:  newTestLeaf(t, now.Add(-time.Hour), now.Add(24*time.Hour+time.Hour), ca),
+			chain: []*testCert{ca},
+			url: func(ts *httptest.Server) string {
+				_, port, _ := net.SplitHostPort(ts.Listener.Addr().String())
+				return "https://localhost:" + port
+			},
+			failure:  TLSFailureHostnameMismatch,
+			subject:  "CN=example.test",
+			daysLeft: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := newTestTLSServer(t, tt.leaf, tt.chain...)
+			defer ts.Close()
+
+			urlToCheck := ts.URL
+			if tt.url != nil {
+				urlToCheck = tt.url(ts)
+			}
+
+			// The handshake fails, with the certificate described on the result
+			verifier := NewVerifier(
+				WithHTTPCheckInternal(),
+				WithTransport(newTrustingTransport(ca)),
+				WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+			)
+			ret, err := verifier.CheckHTTP(urlToCheck)
+
+			assert.ErrorIs(t, err, ErrTLS)
+			assert.False(t, ret.Reachable)
+			if assert.NotNil(t, ret.TLS) {
+				assert.False(t, ret.TLS.ChainValid)
+				assert.Equal(t, tt.failure, ret.TLS.Failure)
+				assert.Equal(t, tt.subject, ret.TLS.Subject)
+				assert.Equal(t, tt.daysLeft, ret.TLS.DaysUntilExpiry)
+			}
+
+			// Skipping verification, the certificate is still verified
+			verifier = NewVerifier(
+				WithHTTPCheckInternal(),
+				WithTransport(newTrustingTransport(ca)),
+				WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+				WithSkipCertVerification(),
+			)
+			ret, err = verifier.CheckHTTP(urlToCheck)
+
+			assert.Nil(t, err)
+			assert.True(t, ret.IsSuccess)
+			if assert.NotNil(t, ret.TLS) {
+				assert.False(t, ret.TLS.ChainValid)
+				assert.Equal(t, tt.failure, ret.TLS.Failure)
+				assert.Equal(t, "TLS 1.3", ret.TLS.Version)
+			}
+		})
+	}
+}
+
+func TestCheckHTTP_TLSNotHTTPS(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Nil(t, ret.TLS)
+}
@@ -0,0 +1,174 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// TrackingRule describes tracking parameters removed from URLs by
+// StripTracking
+type TrackingRule struct {
+	// Domain is the domain the rule applies to, including its subdomains, or
+	// empty for all domains. A trailing ".*" matches any suffix, e.g.
+	// "amazon.*" matches amazon.com and www.amazon.co.uk.
+	Domain string
+	// Params are the names of the query parameters removed, matched
+	// case-insensitively. A trailing "*" matches any suffix, e.g. "utm_*".
+	Params []string
+	// PathPattern matches parts of the escaped path which are removed, e.g.
+	// the "/ref=..." segment of Amazon product URLs
+	PathPattern *regexp.Regexp
+}
+
+// amazonRefPath matches the referral segment of Amazon paths
+var amazonRefPath = regexp.MustCompile(`/ref=[^/]*$`)
+
+// DefaultTrackingRules returns the built-in tracking rules, removing common
+// analytics and ad click parameters from all URLs, and referral parameters of
+// some sites
+func DefaultTrackingRules() []TrackingRule {
+	return []TrackingRule{
+		{Params: []string{
+			// Google Analytics and Ads
+			"utm_*", "gclid", "gclsrc", "dclid", "gbraid", "wbraid", "_ga", "_gl",
+			// Facebook, Instagram, Microsoft, Twitter, TikTok, LinkedIn and Yandex
+			"fbclid", "igshid", "msclkid", "twclid", "ttclid", "li_fat_id", "yclid",
+			// Mailchimp, HubSpot, Marketo and Vero
+			"mc_cid", "mc_eid", "_hsenc", "_hsmi", "__hssc", "__hstc", "__hsfp", "hsctatracking", "mkt_tok", "vero_id", "vero_conv",
+			// Olytics and Adobe
+			"oly_anon_id", "oly_enc_id", "s_cid",
+		}},
+		{
+			Domain:      "amazon.*",
+			Params:      []string{"ref", "ref_", "pf_rd_*", "pd_rd_*", "_encoding", "psc", "qid", "sr", "crid", "sprefix", "content-id"},
+			PathPattern: amazonRefPath,
+		},
+		{Domain: "youtube.com", Params: []string{"si", "feature"}},
+		{Domain: "youtu.be", Params: []string{"si", "feature"}},
+		{Domain: "twitter.com", Params: []string{"s", "t", "ref_src"}},
+		{Domain: "x.com", Params: []string{"s", "t", "ref_src"}},
+	}
+}
+
+// StripTracking removes the tracking parameters matched by the rules from the
+// URL, returning the cleaned URL and the removed parameters as they appeared in
+// the URL, e.g. "utm_source=newsletter". The rest of the URL is unchanged.
+func StripTracking(rawURL string, rules []TrackingRule) (string, []string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	applicable := []TrackingRule{}
+	for _, rule := range rules {
+		if rule.matchesDomain(host) {
+			applicable = append(applicable, rule)
+		}
+	}
+
+	var removed []string
+
+	if u.RawQuery != "" {
+		kept := []string{}
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			if param == "" {
+				continue
+			}
+
+			name := param
+			if i := strings.IndexByte(param, '='); i >= 0 {
+				name = param[:i]
+			}
+			if unescaped, err := url.QueryUnescape(name); err == nil {
+				name = unescaped
+			}
+
+			if trackingParam(applicable, name) {
+				removed = append(removed, param)
+			} else {
+				kept = append(kept, param)
+			}
+		}
+
+		if len(removed) > 0 {
+			u.RawQuery = strings.Join(kept, "&")
+		}
+	}
+
+	path := u.EscapedPath()
+	for _, rule := range applicable {
+		if rule.PathPattern == nil {
+			continue
+		}
+		for _, match := range rule.PathPattern.FindAllString(path, -1) {
+			removed = append(removed, strings.TrimPrefix(match, "/"))
+		}
+		path = rule.PathPattern.ReplaceAllString(path, "")
+	}
+	if path != u.EscapedPath() {
+		unescaped, err := url.PathUnescape(path)
+		if err != nil {
+			return "", nil, err
+		}
+		u.Path = unescaped
+		u.RawPath = path
+	}
+
+	return u.String(), removed, nil
+}
+
+// matchesDomain reports whether the rule applies to the host
+func (r TrackingRule) matchesDomain(host string) bool {
+	domain := strings.ToLower(r.Domain)
+	if domain == "" {
+		return true
+	}
+
+	if prefix := strings.TrimSuffix(domain, ".*"); prefix != domain {
+		// Match the labels of the domain followed by any suffix
+		for {
+			if strings.HasPrefix(host, prefix+".") {
+				return true
+			}
+			i := strings.IndexByte(host, '.')
+			if i < 0 {
+				return false
+			}
+			host = host[i+1:]
+		}
+	}
+
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// trackingParam reports whether the query parameter is removed by any of the
+// rules
+func trackingParam(rules []TrackingRule, name string) bool {
+	name = strings.ToLower(name)
+	for _, rule := range rules {
+		for _, param := range rule.Params {
+			param = strings.ToLower(param)
+			if prefix := strings.TrimSuffix(param, "*"); prefix != param {
+				if strings.HasPrefix(name, prefix) {
+					return true
+				}
+			} else if name == param {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// String returns a description of the rule, used in cache keys
+func (r TrackingRule) String() string {
+	pattern := ""
+	if r.PathPattern != nil {
+		pattern = r.PathPattern.String()
+	}
+	return fmt.Sprintf("{%s %v %s}", r.Domain, r.Params, pattern)
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripTracking(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawURL   string
+		expected string
+		removed  []string
+	}{
+		{
+			"no tracking",
+			"https://example.com/path?q=go&page=2#top",
+			"https://example.com/path?q=go&page=2#top",
+			nil,
+		},
+		{
+			"UTM parameters",
+			"https://example.com/?utm_source=newsletter&id=1&UTM_Medium=email&utm_campaign=launch",
+			"https://example.com/?id=1",
+			[]string{"utm_source=newsletter", "UTM_Medium=email", "utm_campaign=launch"},
+		},
+		{
+			"click IDs",
+			"https://example.com/page?fbclid=abc&gclid=def&mc_eid=ghi&msclkid=jkl#section",
+			"https://example.com/page#section",
+			[]string{"fbclid=abc", "gclid=def", "mc_eid=ghi", "msclkid=jkl"},
+		},
+		{
+			"escaped name",
+			"https://example.com/?utm%5Fsource=x&a=%20b",
+			"https://example.com/?a=%20b",
+			[]string{"utm%5Fsource=x"},
+		},
+		{
+			"domain rule does not apply to other domains",
+			"https://example.com/dp/B000/ref=sr_1_1?ref=x&si=y",
+			"https://example.com/dp/B000/ref=sr_1_1?ref=x&si=y",
+			nil,
+		},
+		{
+			"Amazon",
+			"https://www.amazon.co.uk/Some-Product/dp/B000123/ref=sr_1_3?crid=2X&keywords=lamp&qid=1690000000&sr=8-3",
+			"https://www.amazon.co.uk/Some-Product/dp/B000123?keywords=lamp",
+			[]string{"crid=2X", "qid=1690000000", "sr=8-3", "ref=sr_1_3"},
+		},
+		{
+			"YouTube",
+			"https://youtu.be/dQw4w9WgXcQ?si=abc123&t=42",
+			"https://youtu.be/dQw4w9WgXcQ?t=42",
+			[]string{"si=abc123"},
+		},
+		{
+			"subdomain",
+			"https://m.youtube.com/watch?v=dQw4w9WgXcQ&feature=share",
+			"https://m.youtube.com/watch?v=dQw4w9WgXcQ",
+			[]string{"feature=share"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cleaned, removed, err := StripTracking(tt.rawURL, DefaultTrackingRules())
+			assert.Nil(t, err)
+			assert.Equal(t, tt.expected, cleaned)
+			assert.Equal(t, tt.removed, removed)
+		})
+	}
+}
+
+func TestStripTracking_CustomRules(t *testing.T) {
+	rules := append(DefaultTrackingRules(), TrackingRule{
+		Domain:      "shop.example",
+		Params:      []string{"aff_*"},
+		PathPattern: regexp.MustCompile(`/session-[0-9a-f]+`),
+	})
+
+	cleaned, removed, err := StripTracking("https://www.shop.example/session-abc123/item?aff_id=7&color=red&utm_source=x", rules)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.shop.example/item?color=red", cleaned)
+	assert.Equal(t, []string{"aff_id=7", "utm_source=x", "session-abc123"}, removed)
+
+	// The rule does not apply to similarly named domains
+	cleaned, removed, err = StripTracking("https://notshop.example/?aff_id=7", rules)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://notshop.example/?aff_id=7", cleaned)
+	assert.Nil(t, removed)
+}
+
+func TestTrackingRule_matchesDomain(t *testing.T) {
+	amazon := TrackingRule{Domain: "amazon.*"}
+	assert.True(t, amazon.matchesDomain("amazon.com"))
+	assert.True(t, amazon.matchesDomain("www.amazon.co.jp"))
+	assert.False(t, amazon.matchesDomain("notamazon.com"))
+	assert.False(t, amazon.matchesDomain("amazon"))
+
+	example := TrackingRule{Domain: "Example.com"}
+	assert.True(t, example.matchesDomain("example.com"))
+	assert.True(t, example.matchesDomain("a.b.example.com"))
+	assert.False(t, example.matchesDomain("example.com.evil"))
+
+	assert.True(t, TrackingRule{}.matchesDomain("anything.example"))
+}
+
+func TestVerify_StripTracking(t *testing.T) {
+	rawURL := "https://example.com/?utm_source=newsletter&id=1"
+
+	ret, err := NewVerifier().Verify(rawURL)
+	assert.Nil(t, err)
+	assert.Empty(t, ret.CleanURL)
+	assert.Nil(t, ret.RemovedParams)
+
+	ret, err = NewVerifier(WithStripTracking()).Verify(rawURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/?id=1", ret.CleanURL)
+	assert.Equal(t, []string{"utm_source=newsletter"}, ret.RemovedParams)
+
+	// Custom rules replace the built-in ones
+	ret, err = NewVerifier(WithStripTracking(TrackingRule{Params: []string{"id"}})).Verify(rawURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://example.com/?utm_source=newsletter", ret.CleanURL)
+	assert.Equal(t, []string{"id=1"}, ret.RemovedParams)
+}
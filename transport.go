@@ -0,0 +1,184 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// proxiedKey marks the context of a request which is sent through a proxy
+type proxiedKey struct{}
+
+// guardRoundTripper checks the host of each request against the IP policy
+// before passing it on. It is used where the policy cannot be enforced at dial
+// time: requests sent through a proxy, which resolves the host itself, and
+// transports which are not an *http.Transport or which use custom TLS dial
+// functions.
+type guardRoundTripper struct {
+	v    *Verifier
+	next http.RoundTripper
+	// proxy is the Proxy function of the wrapped *http.Transport. If set, only
+	// requests sent through a proxy are checked because the others are checked
+	// at dial time. If nil, all requests are checked.
+	proxy func(*http.Request) (*url.URL, error)
+}
+
+func (g *guardRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !g.v.ipPolicyEnabled() {
+		return g.next.RoundTrip(req)
+	}
+
+	if g.proxy != nil {
+		proxyURL, err := g.proxy(req)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL == nil {
+			return g.next.RoundTrip(req)
+		}
+		req = req.WithContext(context.WithValue(req.Context(), proxiedKey{}, true))
+	}
+
+	if _, err := g.v.checkHost(req.Context(), req.URL.Hostname()); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	return g.next.RoundTrip(req)
+}
+
+// httpCheckClient returns the client shared by HTTP checks, creating it on
+// first use so connections are pooled between checks. The client is recreated
+// if certificate verification has been toggled since it was created.
+func (v *Verifier) httpCheckClient() *http.Client {
+	v.clientMu.Lock()
+	defer v.clientMu.Unlock()
+
+	if v.client != nil && v.clientSkipCertVerification == v.skipCertVerification {
+		return v.client
+	}
+
+	if v.clientCloseIdle != nil {
+		v.clientCloseIdle()
+	}
+	v.client, v.clientCloseIdle = v.newClient()
+	v.clientSkipCertVerification = v.skipCertVerification
+	return v.client
+}
+
+// Close closes the idle connections pooled by the HTTP check transport. The
+// Verifier can still be used afterwards, with new connections being opened as
+// needed. Transports supplied with WithTransport() or WithHTTPClient() are
+// cloned, so their own idle connections are not affected, unless they are not
+// an *http.Transport.
+func (v *Verifier) Close() error {
+	v.clientMu.Lock()
+	defer v.clientMu.Unlock()
+
+	if v.clientCloseIdle != nil {
+		v.clientCloseIdle()
+	}
+	return nil
+}
+
+// newClient creates the client used by the HTTP check, along with a function
+// to close the idle connections of its transport. It is based on the client
+// set with WithHTTPClient and the transport set with WithTransport, if any,
+// or else a transport using the proxy set in the environment, like
+// http.DefaultTransport. The internal IP policy and rate limiter are layered on
+// top of the transport and the redirect policy is applied before the client's
+// own CheckRedirect.
+// Redirects are recorded with the redirectRecorder of the request context.
+//
+// An *http.Transport is cloned and its DialContext, or else its Dial, wrapped
+// so the policy is enforced at dial time. Any other http.RoundTripper is wrapped so the policy
+// is enforced before each request is sent, which does not protect against DNS
+// rebinding.
+func (v *Verifier) newClient() (*http.Client, func()) {
+	client := &http.Client{}
+	if v.httpClient != nil {
+		*client = *v.httpClient
+	}
+
+	rt := client.Transport
+	if v.transport != nil {
+		rt = v.transport
+	}
+
+	closeIdle := func() {}
+	if rt == nil {
+		rt = &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConns:          v.maxIdleConns,
+			MaxIdleConnsPerHost:   v.maxIdleConnsPerHost,
+			IdleConnTimeout:       v.idleConnTimeout,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		}
+	}
+
+	if t, ok := rt.(*http.Transport); ok {
+		transport := t.Clone()
+		customTLSDial := transport.DialTLS != nil || transport.DialTLSContext != nil //nolint:staticcheck // Deprecated fields are still honored by http.Transport
+
+		// Enforce the internal IP policy at dial time. DialContext takes
+		// precedence over the deprecated Dial, so a Dial without a DialContext
+		// is wrapped instead of being dropped.
+		dialContext := transport.DialContext
+		if dial := transport.Dial; dial != nil && dialContext == nil { //nolint:staticcheck // Deprecated fields are still honored by http.Transport
+			dialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dial(network, addr)
+			}
+		}
+		transport.DialContext = v.guardDial(dialContext)
+
+		// Skip certificate verification if allowed
+		if v.skipCertVerification {
+			if transport.TLSClientConfig == nil {
+				transport.TLSClientConfig = &tls.Config{}
+			}
+			transport.TLSClientConfig.InsecureSkipVerify = true
+		}
+
+		closeIdle = transport.CloseIdleConnections
+		rt = transport
+
+		switch {
+		case customTLSDial:
+			rt = &guardRoundTripper{v: v, next: transport}
+		case transport.Proxy != nil:
+			rt = &guardRoundTripper{v: v, next: transport, proxy: transport.Proxy}
+		}
+	} else {
+		rt = &guardRoundTripper{v: v, next: rt}
+	}
+
+	if v.limiter != nil {
+		rt = &rateLimitRoundTripper{v: v, next: rt}
+	}
+
+	checkRedirect := client.CheckRedirect
+	client.Transport = rt
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		err := v.checkRedirect(req, via)
+		if err == nil && checkRedirect != nil {
+			err = checkRedirect(req, via)
+		}
+
+		// Redirects which are not followed because the response is used
+		// instead are not recorded
+		if !errors.Is(err, http.ErrUseLastResponse) {
+			recordRedirect(req, via)
+		}
+		return err
+	}
+
+	return client, closeIdle
+}
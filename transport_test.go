@@ -0,0 +1,271 @@
+// SPDX-License-Identifier: MIT
+package urlverifier
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingRoundTripper counts the requests passed to the next RoundTripper
+type countingRoundTripper struct {
+	next     http.RoundTripper
+	requests int32
+}
+
+func (c *countingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&c.requests, 1)
+	return c.next.RoundTrip(req)
+}
+
+func TestWithTransport_RoundTripperInternalIPPolicy(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	rt := &countingRoundTripper{next: &http.Transport{}}
+
+	verifier := NewVerifier(WithTransport(rt))
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.ErrorContains(t, err, "resolves to an internal IP 127.0.0.1")
+	assert.Equal(t, int32(0), atomic.LoadInt32(&rt.requests))
+}
+
+func TestWithTransport_RoundTripperInternalAllowed(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	rt := &countingRoundTripper{next: &http.Transport{}}
+
+	verifier := NewVerifier(WithTransport(rt), WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: ts.URL}, withoutTiming(ret))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&rt.requests))
+}
+
+func TestWithTransport_CustomDialContext(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The custom dial function is called with the vetted IP
+	dialed := ""
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			dialed = addr
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+
+	urlToCheck := fmt.Sprintf("http://dial.example:%s/", tsURL.Port())
+
+	verifier := NewVerifier(
+		WithTransport(transport),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+	)
+	ret, err := verifier.CheckHTTP(urlToCheck)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: urlToCheck}, withoutTiming(ret))
+	assert.Equal(t, "127.0.0.1:"+tsURL.Port(), dialed)
+}
+
+func TestWithTransport_CustomDial(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	tsURL, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The deprecated Dial is used, with the vetted IP, rather than dropped
+	dialed := ""
+	transport := &http.Transport{
+		Dial: func(network, addr string) (net.Conn, error) {
+			dialed = addr
+			return net.Dial(network, addr)
+		},
+	}
+
+	urlToCheck := fmt.Sprintf("http://dial.example:%s/", tsURL.Port())
+
+	verifier := NewVerifier(
+		WithTransport(transport),
+		WithHTTPCheckInternal(),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+	)
+	ret, err := verifier.CheckHTTP(urlToCheck)
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: urlToCheck}, withoutTiming(ret))
+	assert.Equal(t, "127.0.0.1:"+tsURL.Port(), dialed)
+
+	// Internal IPs are still refused
+	verifier = NewVerifier(
+		WithTransport(transport),
+		WithResolver(&stubResolver{responses: [][]string{{"127.0.0.1"}}}),
+	)
+	dialed = ""
+	_, err = verifier.CheckHTTP(urlToCheck)
+
+	assert.ErrorIs(t, err, ErrInternalIP)
+	assert.Equal(t, "", dialed)
+}
+
+func TestWithTransport_TLSConfig(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	// The test server certificate is only trusted by the supplied transport
+	transport := &http.Transport{
+		TLSClientConfig: ts.Client().Transport.(*http.Transport).TLSClientConfig.Clone(),
+	}
+
+	verifier := NewVerifier(WithTransport(transport), WithHTTPCheckInternal())
+	ret, err := verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.True(t, ret.TLS.ChainValid)
+
+	// Skipping certificate verification does not modify the supplied transport
+	verifier = NewVerifier(WithTransport(&http.Transport{TLSClientConfig: &tls.Config{}}), WithHTTPCheckInternal(), WithSkipCertVerification())
+	ret, err = verifier.CheckHTTP(ts.URL)
+
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.False(t, ret.TLS.ChainValid)
+	assert.False(t, verifier.transport.(*http.Transport).TLSClientConfig.InsecureSkipVerify)
+}
+
+func TestWithTransport_Proxy(t *testing.T) {
+	// The proxy listens on an internal IP, which is allowed because it is
+	// configured by the caller. The hosts requested through it are checked.
+	proxied := ""
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer proxy.Close()
+
+	proxyURL, err := url.Parse(proxy.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewVerifier(
+		WithTransport(&http.Transport{Proxy: http.ProxyURL(proxyURL)}),
+		WithResolver(&stubResolver{responses: [][]string{{"93.184.216.34"}}}),
+	)
+	ret, err := verifier.CheckHTTP("http://public.example/")
+
+	assert.Nil(t, err)
+	assert.Equal(t, &HTTP{Reachable: true, StatusCode: 200, IsSuccess: true, Method: "GET", FinalURL: "http://public.example/"}, withoutTiming(ret))
+	assert.Equal(t, "http://public.example/", proxied)
+
+	ret, err = verifier.CheckHTTP("http://10.0.0.5/admin")
+
+	assert.Equal(t, &HTTP{Reachable: false, IsSuccess: false, Method: "GET"}, withoutTiming(ret))
+	assert.ErrorContains(t, err, "resolves to an internal IP 10.0.0.5")
+	assert.Equal(t, "http://public.example/", proxied)
+}
+
+func TestHTTPCheckClient_PooledConnections(t *testing.T) {
+	var conns int32
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	defer verifier.Close()
+
+	for i := 0; i < 3; i++ {
+		ret, err := verifier.CheckHTTP(ts.URL)
+		assert.Nil(t, err)
+		assert.True(t, ret.IsSuccess)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&conns))
+
+	// Closing releases the pooled connection, the verifier can still be used
+	assert.Nil(t, verifier.Close())
+
+	ret, err := verifier.CheckHTTP(ts.URL)
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&conns))
+}
+
+func TestHTTPCheckClient_TransportOptions(t *testing.T) {
+	verifier := NewVerifier(
+		WithMaxIdleConns(10),
+		WithMaxIdleConnsPerHost(5),
+		WithIdleConnTimeout(time.Minute),
+	)
+
+	client := verifier.httpCheckClient()
+	guard := client.Transport.(*guardRoundTripper)
+	transport := guard.next.(*http.Transport)
+
+	// The proxy set in the environment is used, as with http.DefaultTransport,
+	// and requests sent through it are checked
+	assert.NotNil(t, transport.Proxy)
+	assert.NotNil(t, guard.proxy)
+	assert.Equal(t, 10, transport.MaxIdleConns)
+	assert.Equal(t, 5, transport.MaxIdleConnsPerHost)
+	assert.Equal(t, time.Minute, transport.IdleConnTimeout)
+	assert.Same(t, client, verifier.httpCheckClient())
+}
+
+func TestHTTPCheckClient_SkipCertVerificationToggled(t *testing.T) {
+	ts := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "Hello, client")
+	}))
+	defer ts.Close()
+
+	verifier := NewVerifier(WithHTTPCheckInternal())
+	defer verifier.Close()
+
+	ret, err := verifier.CheckHTTP(ts.URL)
+	assert.False(t, ret.Reachable)
+	assert.ErrorContains(t, err, "x509:")
+
+	verifier.AllowSkipCertVerification()
+	ret, err = verifier.CheckHTTP(ts.URL)
+	assert.Nil(t, err)
+	assert.True(t, ret.IsSuccess)
+}
@@ -4,34 +4,136 @@
 package urlverifier
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"net/netip"
 	"net/url"
+	"sync"
+	"time"
 
 	"github.com/asaskevich/govalidator"
+	"github.com/davidmytton/url-verifier/internal/ratelimit"
 )
 
 // Verifier is a URL Verifier. Create one using NewVerifier()
 type Verifier struct {
-	httpCheckEnabled       bool // Whether to check if the URL is reachable via HTTP (default: false)
-	allowHttpCheckInternal bool // Whether to allow HTTP checks to hosts that resolve to internal IPs (default: false)
-	skipCertVerification   bool // Whether to skip certificate verification when checking HTTP (default: false)
+	httpCheckEnabled       bool               // Whether to check if the URL is reachable via HTTP (default: false)
+	allowHttpCheckInternal bool               // Whether to allow HTTP checks to hosts that resolve to internal IPs (default: false)
+	skipCertVerification   bool               // Whether to skip certificate verification when checking HTTP (default: false)
+	resolver               Resolver           // The resolver used to look up hosts (default: net.DefaultResolver)
+	timeout                time.Duration      // The maximum duration of the reachability check, 0 for no timeout (default: DefaultTimeout)
+	httpClient             *http.Client       // The HTTP client the HTTP check is based on (default: nil)
+	transport              http.RoundTripper  // The transport used by the HTTP check, overriding the client's (default: nil)
+	userAgent              string             // The User-Agent header sent by the HTTP check (default: Go's default)
+	maxIdleConns           int                // The maximum number of idle connections kept by the HTTP check transport (default: DefaultMaxIdleConns)
+	maxIdleConnsPerHost    int                // The maximum number of idle connections per host kept by the HTTP check transport (default: DefaultMaxIdleConnsPerHost)
+	idleConnTimeout        time.Duration      // How long idle connections are kept by the HTTP check transport (default: DefaultIdleConnTimeout)
+	allowedCIDRs           []netip.Prefix     // IP ranges HTTP checks are allowed to, even if internal (default: none)
+	deniedCIDRs            []netip.Prefix     // IP ranges HTTP checks are denied to, taking precedence over allowed ranges (default: none)
+	maxRedirects           int                // The maximum number of redirects followed by the HTTP check (default: DefaultMaxRedirects)
+	methodStrategy         MethodStrategy     // The HTTP method strategy of the HTTP check (default: MethodGET)
+	getOnlyHosts           []string           // Hosts the HTTP check always sends GET requests to with MethodHEADThenGET (default: none)
+	maxBodyBytes           int64              // The maximum number of bytes of a response body read by the HTTP check (default: DefaultMaxBodyBytes)
+	concurrency            int                // The maximum number of URLs verified at once by VerifyAll and VerifyStream, 0 for no limit (default: DefaultConcurrency)
+	hostConcurrency        int                // The maximum number of URLs per host verified at once by VerifyAll and VerifyStream, 0 for no limit (default: DefaultHostConcurrency)
+	rateLimit              float64            // The maximum number of HTTP check requests per second per host, 0 for no limit (default: 0)
+	rateLimitBurst         int                // The number of HTTP check requests per host which may exceed the rate limit at once (default: 1)
+	rateLimitByIP          bool               // Whether to rate limit per IP the host resolves to instead of per host (default: false)
+	limiter                *ratelimit.Limiter // The rate limiter, if rate limiting is enabled
+	retryPolicy            *RetryPolicy       // The policy for retrying HTTP checks which fail transiently (default: no retries)
+	cache                  Cache              // The cache of Verify results (default: no cache)
+	cacheTTL               time.Duration      // How long successful results are cached (default: DefaultCacheTTL)
+	negativeCacheTTL       time.Duration      // How long unsuccessful results are cached (default: DefaultNegativeCacheTTL)
+	flights                flightGroup        // Verifications in flight, collapsed when the cache is enabled
+	normalizeOptions       NormalizeOptions   // The normalizations applied to Result.NormalizedURL beyond the safe ones (default: none)
+	stripTracking          bool               // Whether to strip tracking parameters into Result.CleanURL (default: false)
+	trackingRules          []TrackingRule     // The rules tracking parameters are stripped with (default: DefaultTrackingRules())
+	publicSuffixList       *PublicSuffixList  // The Public Suffix List of Result.Domain (default: DefaultPublicSuffixList())
+	tldList                *TLDList           // The TLD list of Result.HasKnownTLD (default: DefaultTLDList())
+	requireKnownTLD        bool               // Whether URLs with a domain name host without a known TLD are invalid (default: false)
+
+	clientMu                   sync.Mutex   // Guards the fields below
+	client                     *http.Client // The client shared by HTTP checks, created on first use
+	clientCloseIdle            func()       // Closes the idle connections of the shared client's transport
+	clientSkipCertVerification bool         // The value of skipCertVerification the shared client was created with
 }
 
+const (
+	// DefaultTimeout is the default maximum duration of the reachability check
+	DefaultTimeout = 30 * time.Second
+	// DefaultMaxIdleConns is the default maximum number of idle connections
+	// kept by the HTTP check transport
+	DefaultMaxIdleConns = 100
+	// DefaultMaxIdleConnsPerHost is the default maximum number of idle
+	// connections per host kept by the HTTP check transport
+	DefaultMaxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+	// DefaultIdleConnTimeout is the default duration idle connections are kept
+	// by the HTTP check transport
+	DefaultIdleConnTimeout = 90 * time.Second
+	// DefaultMaxRedirects is the default maximum number of redirects followed
+	// by the HTTP check
+	DefaultMaxRedirects = 10
+	// DefaultMaxBodyBytes is the default maximum number of bytes of a response
+	// body read by the HTTP check
+	DefaultMaxBodyBytes = 64 << 10
+	// DefaultConcurrency is the default maximum number of URLs verified at once
+	// by VerifyAll and VerifyStream
+	DefaultConcurrency = 10
+	// DefaultHostConcurrency is the default maximum number of URLs per host
+	// verified at once by VerifyAll and VerifyStream
+	DefaultHostConcurrency = 2
+	// DefaultCacheTTL is the default duration successful results are cached
+	DefaultCacheTTL = 5 * time.Minute
+	// DefaultNegativeCacheTTL is the default duration unsuccessful results,
+	// i.e. errors, invalid URLs and unsuccessful HTTP checks, are cached
+	DefaultNegativeCacheTTL = 30 * time.Second
+)
+
 // Result is the result of a URL verification
 type Result struct {
 	URL           string   `json:"url"`            // The URL that was checked
 	URLComponents *url.URL `json:"url_components"` // The URL components, if the URL is valid
+	NormalizedURL string   `json:"normalized_url"` // The URL normalized with Normalize, if the URL is valid
+	CleanURL      string   `json:"clean_url"`      // The URL without tracking parameters, if stripping them is enabled and the URL is valid
+	RemovedParams []string `json:"removed_params"` // The tracking parameters removed from CleanURL
+	Domain        *Domain  `json:"domain"`         // The breakdown of the host by the Public Suffix List, if the URL is valid and its host is a domain name
+	HasKnownTLD   bool     `json:"has_known_tld"`  // Whether the host is a domain name with a TLD in the TLD list
 	IsURL         bool     `json:"is_url"`         // Whether the URL is valid
 	IsRFC3986URL  bool     `json:"is_rfc3986_url"` // Whether the URL is a valid URL according to RFC 3986. This is the same as IsRFC3986URI but with a check for a scheme.
 	IsRFC3986URI  bool     `json:"is_rfc3986_uri"` // Whether the URL is a valid URI according to RFC 3986
 	HTTP          *HTTP    `json:"http"`           // The result of a HTTP check, if enabled
+	Refused       *Refusal `json:"refused"`        // Why the HTTP check refused to connect to a host, if it did
+	Cached        bool     `json:"cached"`         // Whether the result was returned from the cache
+	Err           error    `json:"-"`              // The error verifying the URL, if any. Only set by VerifyAll and VerifyStream, as Verify returns it.
 }
 
-// NewVerifier creates a new URL Verifier
-func NewVerifier() *Verifier {
-	return &Verifier{allowHttpCheckInternal: false, skipCertVerification: false}
+// NewVerifier creates a new URL Verifier, configured with the given options
+func NewVerifier(opts ...Option) *Verifier {
+	v := &Verifier{
+		allowHttpCheckInternal: false,
+		skipCertVerification:   false,
+		resolver:               net.DefaultResolver,
+		timeout:                DefaultTimeout,
+		maxIdleConns:           DefaultMaxIdleConns,
+		maxIdleConnsPerHost:    DefaultMaxIdleConnsPerHost,
+		idleConnTimeout:        DefaultIdleConnTimeout,
+		maxRedirects:           DefaultMaxRedirects,
+		methodStrategy:         MethodGET,
+		maxBodyBytes:           DefaultMaxBodyBytes,
+		concurrency:            DefaultConcurrency,
+		hostConcurrency:        DefaultHostConcurrency,
+		cacheTTL:               DefaultCacheTTL,
+		negativeCacheTTL:       DefaultNegativeCacheTTL,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	if v.rateLimit > 0 {
+		v.limiter = ratelimit.New(v.rateLimit, v.rateLimitBurst)
+	}
+	return v
 }
 
 // Verify verifies a URL. It checks if the URL is valid, parses it if so, and
@@ -39,6 +141,22 @@ func NewVerifier() *Verifier {
 // URL with a scheme). If the HTTP check is enabled, it also checks if the URL
 // is reachable via HTTP.
 func (v *Verifier) Verify(rawURL string) (*Result, error) {
+	return v.VerifyContext(context.Background(), rawURL)
+}
+
+// VerifyContext verifies a URL in the same way as Verify. The context controls
+// the DNS lookup and HTTP check, which are also limited by the verifier
+// timeout. If a cache is set, the result is cached and concurrent
+// verifications of the same URL are collapsed into one.
+func (v *Verifier) VerifyContext(ctx context.Context, rawURL string) (*Result, error) {
+	if v.cache != nil {
+		return v.verifyCached(ctx, rawURL)
+	}
+	return v.verify(ctx, rawURL)
+}
+
+// verify verifies a URL without the cache
+func (v *Verifier) verify(ctx context.Context, rawURL string) (*Result, error) {
 	ret := Result{
 		URL:          rawURL,
 		IsURL:        false,
@@ -46,6 +164,51 @@ func (v *Verifier) Verify(rawURL string) (*Result, error) {
 		IsRFC3986URI: false,
 	}
 
+	if err := v.parse(&ret); err != nil {
+		return &ret, err
+	}
+
+	// Check if the URL is reachable via HTTP
+	if v.httpCheckEnabled {
+		if v.requireKnownTLD && ret.Domain != nil && !ret.HasKnownTLD {
+			host := ret.URLComponents.Hostname()
+			err := fmt.Errorf("unable to check if the URL is reachable via HTTP: %w", ErrUnknownTLD)
+			return &ret, &Error{Phase: PhaseParse, URL: ret.URL, Host: host, Kind: ErrUnknownTLD, Err: err}
+		}
+
+		if ret.URLComponents != nil && (ret.URLComponents.Scheme == "http" || ret.URLComponents.Scheme == "https") {
+			ctx, cancel := v.withTimeout(ctx)
+			defer cancel()
+
+			if v.ipPolicyEnabled() {
+				// Lookup host IP. The HTTP check enforces the same policy again
+				// when dialing, so this only fails early.
+				host := ret.URLComponents.Hostname()
+				if _, err := v.checkHost(ctx, host); err != nil {
+					ret.Refused = refusalFromError(err)
+					return &ret, newError(PhaseDNS, ret.URL, host, nil, err)
+				}
+			}
+
+			http, err := v.CheckHTTPContext(ctx, ret.URL)
+			if err != nil {
+				ret.HTTP = http
+				ret.Refused = refusalFromError(err)
+				return &ret, err
+			}
+			ret.HTTP = http
+		} else {
+			err := fmt.Errorf("unable to check if the URL is reachable via HTTP: %w", ErrUnsupportedScheme)
+			return &ret, &Error{Phase: PhaseScheme, URL: ret.URL, Kind: ErrUnsupportedScheme, Err: err}
+		}
+	}
+
+	return &ret, nil
+}
+
+// parse sets the fields of the result which are derived from the URL alone,
+// without network access
+func (v *Verifier) parse(ret *Result) error {
 	// Check if the URL is valid
 	ret.IsURL = govalidator.IsURL(ret.URL)
 
@@ -53,9 +216,23 @@ func (v *Verifier) Verify(rawURL string) (*Result, error) {
 	if ret.IsURL {
 		p, err := url.Parse(ret.URL)
 		if err != nil {
-			return &ret, err
+			return &Error{Phase: PhaseParse, URL: ret.URL, Kind: ErrInvalidURL, Err: err}
 		}
 		ret.URLComponents = p
+		ret.NormalizedURL, _ = Normalize(ret.URL, v.normalizeOptions)
+		if v.stripTracking {
+			ret.CleanURL, ret.RemovedParams, _ = StripTracking(ret.URL, v.trackingRules)
+		}
+		if host := p.Hostname(); host != "" {
+			ret.Domain = v.suffixList().Lookup(host)
+			ret.HasKnownTLD = v.tlds().HasKnownTLD(host)
+
+			// URLs with a domain name host without a known TLD are invalid if
+			// required
+			if v.requireKnownTLD && ret.Domain != nil && !ret.HasKnownTLD {
+				ret.IsURL = false
+			}
+		}
 	}
 
 	// Check if the URL is a valid URI according to RFC 3986, plus a check for a
@@ -64,41 +241,23 @@ func (v *Verifier) Verify(rawURL string) (*Result, error) {
 
 	// Check if the URL is a valid URI according to RFC 3986
 	ret.IsRFC3986URI = v.IsRequestURI(ret.URL)
+	return nil
+}
 
-	// Check if the URL is reachable via HTTP
-	if v.httpCheckEnabled {
-		if ret.URLComponents != nil && (ret.URLComponents.Scheme == "http" || ret.URLComponents.Scheme == "https") {
-			if !v.allowHttpCheckInternal {
-				// Lookup host IP
-				host := ret.URLComponents.Hostname()
-				ips, err := net.LookupIP(host)
-				if err != nil {
-					return &ret, err
-				}
-
-				// Check each IP to see if it is an internal IP
-				for _, ip := range ips {
-					if ip.IsPrivate() || ip.IsLoopback() ||
-						ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
-						ip.IsInterfaceLocalMulticast() || ip.IsUnspecified() {
-						message := fmt.Sprintf("unable to check if the URL is reachable via HTTP: the URL %s resolves to an internal IP %s", host, ip)
-						return &ret, errors.New(message)
-					}
-				}
-			}
-
-			http, err := v.CheckHTTP(ret.URL)
-			if err != nil {
-				ret.HTTP = http
-				return &ret, err
-			}
-			ret.HTTP = http
-		} else {
-			return &ret, errors.New("unable to check if the URL is reachable via HTTP: the URL does not have a HTTP or HTTPS scheme")
-		}
+// suffixList returns the Public Suffix List used by the verifier
+func (v *Verifier) suffixList() *PublicSuffixList {
+	if v.publicSuffixList != nil {
+		return v.publicSuffixList
 	}
+	return DefaultPublicSuffixList()
+}
 
-	return &ret, nil
+// tlds returns the TLD list used by the verifier
+func (v *Verifier) tlds() *TLDList {
+	if v.tldList != nil {
+		return v.tldList
+	}
+	return DefaultTLDList()
 }
 
 // IsRequestURL checks if the string rawURL, assuming it was received in an HTTP
@@ -124,6 +283,14 @@ func (v *Verifier) IsRequestURI(rawURL string) bool {
 	return err == nil
 }
 
+// withTimeout returns a context limited by the verifier timeout, if set
+func (v *Verifier) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if v.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, v.timeout)
+}
+
 // DisableHTTPCheck disables checking if the URL is reachable via HTTP
 func (v *Verifier) DisableHTTPCheck() {
 	v.httpCheckEnabled = false
@@ -153,3 +320,9 @@ func (v *Verifier) AllowSkipCertVerification() {
 func (v *Verifier) DisallowSkipCertVerification() {
 	v.skipCertVerification = false
 }
+
+// SetTimeout sets the maximum duration of the reachability check. A timeout of
+// 0 disables the timeout.
+func (v *Verifier) SetTimeout(timeout time.Duration) {
+	v.timeout = timeout
+}